@@ -0,0 +1,173 @@
+package soup
+
+import (
+	"fmt"
+	netURL "net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attr returns the value of the attribute name, and whether the element has it
+func (r Root) Attr(name string) (string, bool) {
+	if r.Pointer == nil {
+		return "", false
+	}
+	for _, attr := range r.Pointer.Attr {
+		if attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// HasAttr reports whether the element has the attribute name
+func (r Root) HasAttr(name string) bool {
+	_, ok := r.Attr(name)
+	return ok
+}
+
+// SetAttr sets the attribute name to value, adding it if the element doesn't have it yet
+func (r Root) SetAttr(name, value string) error {
+	if r.Pointer == nil || r.Pointer.Type != html.ElementNode {
+		return modificationError("attributes can only be set on elements")
+	}
+	for i := range r.Pointer.Attr {
+		if r.Pointer.Attr[i].Key == name {
+			r.Pointer.Attr[i].Val = value
+			removeAttr(r.Pointer, name, i+1)
+			return nil
+		}
+	}
+	r.Pointer.Attr = append(r.Pointer.Attr, html.Attribute{Key: name, Val: value})
+	return nil
+}
+
+// RemoveAttr removes the attribute name from the element
+func (r Root) RemoveAttr(name string) {
+	if r.Pointer != nil {
+		removeAttr(r.Pointer, name, 0)
+	}
+}
+
+// removeAttr removes the attributes of n called name, starting at index from
+func removeAttr(n *html.Node, name string, from int) {
+	kept := n.Attr[:from]
+	for _, attr := range n.Attr[from:] {
+		if attr.Key != name {
+			kept = append(kept, attr)
+		}
+	}
+	n.Attr = kept
+}
+
+// Classes returns the classes of the element, in the order they are listed
+func (r Root) Classes() []string {
+	class, _ := r.Attr("class")
+	return strings.Fields(class)
+}
+
+// HasClass reports whether the element has the class name
+func (r Root) HasClass(name string) bool {
+	for _, c := range r.Classes() {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// AddClass adds the given classes to the element, skipping those it already has
+func (r Root) AddClass(names ...string) error {
+	classes := r.Classes()
+	for _, name := range names {
+		if !slices.Contains(classes, name) {
+			classes = append(classes, name)
+		}
+	}
+	return r.SetAttr("class", strings.Join(classes, " "))
+}
+
+// RemoveClass removes the given classes from the element
+func (r Root) RemoveClass(names ...string) {
+	if !r.HasAttr("class") {
+		return
+	}
+	var kept []string
+	for _, c := range r.Classes() {
+		remove := false
+		for _, name := range names {
+			remove = remove || c == name
+		}
+		if !remove {
+			kept = append(kept, c)
+		}
+	}
+	r.SetAttr("class", strings.Join(kept, " "))
+}
+
+// AttrInt returns the value of the attribute name as an integer
+func (r Root) AttrInt(name string) (int, error) {
+	val, ok := r.Attr(name)
+	if !ok {
+		return 0, attributeNotFound(name)
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		if debug {
+			panic("Attribute `" + name + "` is not an integer")
+		}
+		return 0, newError(ErrInvalidAttribute, fmt.Sprintf("attribute `%s` is not an integer: %q", name, val))
+	}
+	return i, nil
+}
+
+// AttrURL returns the value of the attribute name as a URL, resolved
+// against the href of the document's <base> element if it has one
+func (r Root) AttrURL(name string) (*netURL.URL, error) {
+	val, ok := r.Attr(name)
+	if !ok {
+		return nil, attributeNotFound(name)
+	}
+	u, err := netURL.Parse(strings.TrimSpace(val))
+	if err != nil {
+		if debug {
+			panic("Attribute `" + name + "` is not a URL")
+		}
+		return nil, newError(ErrInvalidAttribute, fmt.Sprintf("attribute `%s` is not a URL: %q", name, val))
+	}
+	if base := documentBase(r.Pointer); base != nil {
+		u = base.ResolveReference(u)
+	}
+	return u, nil
+}
+
+// documentBase returns the URL in the href of the first <base> element
+// of the document n is part of, if there is a valid one
+func documentBase(n *html.Node) *netURL.URL {
+	top := n
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	base, ok := matchOnce(top, func(c *html.Node) bool {
+		return c.Data == "base" && Root{Pointer: c}.HasAttr("href")
+	})
+	if !ok {
+		return nil
+	}
+	href, _ := Root{Pointer: base}.Attr("href")
+	u, err := netURL.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+func attributeNotFound(name string) error {
+	if debug {
+		panic("Attribute `" + name + "` not found")
+	}
+	return newError(ErrAttributeNotFound, fmt.Sprintf("attribute `%s` not found", name))
+}
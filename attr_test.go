@@ -0,0 +1,106 @@
+package soup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const attrHTML = `<html><head><base href="https://example.com/docs/"></head><body>
+<a id="next" class="nav  next" href="page/2?q=1" data-page="2" data-bad="two">Next</a>
+<img src="/logo.png" width=" 120 ">
+</body></html>`
+
+func TestAttr(t *testing.T) {
+	a := HTMLParse(attrHTML).Find("a")
+	val, ok := a.Attr("id")
+	assert.True(t, ok)
+	assert.Equal(t, "next", val)
+	_, ok = a.Attr("title")
+	assert.False(t, ok)
+	assert.True(t, a.HasAttr("href"))
+	assert.False(t, a.HasAttr("title"))
+	_, ok = Root{}.Attr("id")
+	assert.False(t, ok)
+}
+
+func TestSetAndRemoveAttr(t *testing.T) {
+	page := HTMLParse(attrHTML)
+	a := page.Find("a")
+	assert.NoError(t, a.SetAttr("id", "prev"))
+	assert.NoError(t, a.SetAttr("title", "Previous"))
+	assert.Equal(t, "prev", page.Find("a").Attrs()["id"])
+	assert.Equal(t, "title", a.Pointer.Attr[len(a.Pointer.Attr)-1].Key)
+
+	// duplicates left by earlier writes collapse into one attribute
+	a.Pointer.Attr = append(a.Pointer.Attr, a.Pointer.Attr[0])
+	assert.NoError(t, a.SetAttr("id", "last"))
+	count := 0
+	for _, attr := range a.Pointer.Attr {
+		if attr.Key == "id" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+
+	a.RemoveAttr("title")
+	a.RemoveAttr("missing")
+	assert.False(t, a.HasAttr("title"))
+	assert.True(t, a.HasAttr("href"))
+	assert.Equal(t, ErrInvalidModification, a.FindNextSibling().SetAttr("id", "x").(Error).Type)
+}
+
+func TestClasses(t *testing.T) {
+	a := HTMLParse(attrHTML).Find("a")
+	assert.Equal(t, []string{"nav", "next"}, a.Classes())
+	assert.True(t, a.HasClass("nav"))
+	assert.False(t, a.HasClass("na"))
+
+	assert.NoError(t, a.AddClass("active", "nav"))
+	assert.Equal(t, []string{"nav", "next", "active"}, a.Classes())
+	a.RemoveClass("nav", "next")
+	val, _ := a.Attr("class")
+	assert.Equal(t, "active", val)
+
+	img := HTMLParse(attrHTML).Find("img")
+	assert.Empty(t, img.Classes())
+	img.RemoveClass("nav")
+	assert.False(t, img.HasAttr("class"))
+
+	// repeated names are added once
+	assert.NoError(t, img.AddClass("a", "a", "b", "a"))
+	val, _ = img.Attr("class")
+	assert.Equal(t, "a b", val)
+	assert.NoError(t, img.AddClass("b", "c", "c"))
+	val, _ = img.Attr("class")
+	assert.Equal(t, "a b c", val)
+}
+
+func TestTypedAttr(t *testing.T) {
+	page := HTMLParse(attrHTML)
+	a := page.Find("a")
+	n, err := a.AttrInt("data-page")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = page.Find("img").AttrInt("width")
+	assert.NoError(t, err)
+	assert.Equal(t, 120, n)
+	_, err = a.AttrInt("data-bad")
+	assert.Equal(t, ErrInvalidAttribute, err.(Error).Type)
+	_, err = a.AttrInt("missing")
+	assert.Equal(t, ErrAttributeNotFound, err.(Error).Type)
+
+	u, err := a.AttrURL("href")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/docs/page/2?q=1", u.String())
+	u, err = page.Find("img").AttrURL("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/logo.png", u.String())
+
+	// without a <base> the value is returned as it is
+	u, err = HTMLParse(`<a href="page/2">Next</a>`).Find("a").AttrURL("href")
+	assert.NoError(t, err)
+	assert.Equal(t, "page/2", u.String())
+	_, err = a.AttrURL("missing")
+	assert.Equal(t, ErrAttributeNotFound, err.(Error).Type)
+}
@@ -0,0 +1,268 @@
+package soup
+
+import (
+	"fmt"
+	netURL "net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document is a whole parsed document, holding the doctype and top-level
+// comments along with the root element that HTMLParse returns
+type Document struct {
+	Pointer *html.Node
+	Error   error
+}
+
+// quirksPublicIDs are the beginnings of the public identifiers of doctypes
+// which put browsers in quirks mode, as listed by the HTML spec
+var quirksPublicIDs = []string{
+	"+//silmaril//dtd html pro v0r11 19970101//",
+	"-//as//dtd html 3.0 aswedit + extensions//",
+	"-//advasoft ltd//dtd html 3.0 aswedit + extensions//",
+	"-//ietf//dtd html 2.0 level 1//",
+	"-//ietf//dtd html 2.0 level 2//",
+	"-//ietf//dtd html 2.0 strict level 1//",
+	"-//ietf//dtd html 2.0 strict level 2//",
+	"-//ietf//dtd html 2.0 strict//",
+	"-//ietf//dtd html 2.0//",
+	"-//ietf//dtd html 2.1e//",
+	"-//ietf//dtd html 3.0//",
+	"-//ietf//dtd html 3.2 final//",
+	"-//ietf//dtd html 3.2//",
+	"-//ietf//dtd html 3//",
+	"-//ietf//dtd html level 0//",
+	"-//ietf//dtd html level 1//",
+	"-//ietf//dtd html level 2//",
+	"-//ietf//dtd html level 3//",
+	"-//ietf//dtd html strict level 0//",
+	"-//ietf//dtd html strict level 1//",
+	"-//ietf//dtd html strict level 2//",
+	"-//ietf//dtd html strict level 3//",
+	"-//ietf//dtd html strict//",
+	"-//ietf//dtd html//",
+	"-//metrius//dtd metrius presentational//",
+	"-//microsoft//dtd internet explorer 2.0 html strict//",
+	"-//microsoft//dtd internet explorer 2.0 html//",
+	"-//microsoft//dtd internet explorer 2.0 tables//",
+	"-//microsoft//dtd internet explorer 3.0 html strict//",
+	"-//microsoft//dtd internet explorer 3.0 html//",
+	"-//microsoft//dtd internet explorer 3.0 tables//",
+	"-//netscape comm. corp.//dtd html//",
+	"-//netscape comm. corp.//dtd strict html//",
+	"-//o'reilly and associates//dtd html 2.0//",
+	"-//o'reilly and associates//dtd html extended 1.0//",
+	"-//o'reilly and associates//dtd html extended relaxed 1.0//",
+	"-//sq//dtd html 2.0 hotmetal + extensions//",
+	"-//softquad software//dtd hotmetal pro 6.0::19990601::extensions to html 4.0//",
+	"-//softquad//dtd hotmetal pro 4.0::19971010::extensions to html 4.0//",
+	"-//spyglass//dtd html 2.0 extended//",
+	"-//sun microsystems corp.//dtd hotjava html//",
+	"-//sun microsystems corp.//dtd hotjava strict html//",
+	"-//w3c//dtd html 3 1995-03-24//",
+	"-//w3c//dtd html 3.2 draft//",
+	"-//w3c//dtd html 3.2 final//",
+	"-//w3c//dtd html 3.2//",
+	"-//w3c//dtd html 3.2s draft//",
+	"-//w3c//dtd html 4.0 frameset//",
+	"-//w3c//dtd html 4.0 transitional//",
+	"-//w3c//dtd html experimental 19960712//",
+	"-//w3c//dtd html experimental 970421//",
+	"-//w3c//dtd w3 html//",
+	"-//w3o//dtd w3 html 3.0//",
+	"-//webtechs//dtd mozilla html 2.0//",
+	"-//webtechs//dtd mozilla html//",
+}
+
+// HTMLParseDocument parses the HTML like HTMLParse, but returns the whole
+// document instead of a pointer to its root element
+func HTMLParseDocument(s string) Document {
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		if debug {
+			panic("Unable to parse the HTML")
+		}
+		return Document{Error: newError(ErrUnableToParse, "unable to parse the HTML")}
+	}
+	return Document{Pointer: doc}
+}
+
+// Document returns the document the pointer is part of, such as the one
+// parsed by HTMLParse, HTMLParseReader or XMLParse
+func (r Root) Document() Document {
+	if r.Pointer == nil {
+		return noDocument()
+	}
+	doc := r.Pointer
+	for doc.Parent != nil {
+		doc = doc.Parent
+	}
+	if doc.Type != html.DocumentNode {
+		return noDocument()
+	}
+	return Document{Pointer: doc}
+}
+
+func noDocument() Document {
+	if debug {
+		panic("Not part of a document")
+	}
+	return Document{Error: newError(ErrNoDocument, "not part of a document")}
+}
+
+// Doctype returns a pointer to the doctype of the document, whose
+// NodeValue is its name, such as "html"
+func (d Document) Doctype() Root {
+	if d.Pointer != nil {
+		for c := d.Pointer.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.DoctypeNode {
+				return Root{Pointer: c, NodeValue: c.Data}
+			}
+		}
+	}
+	if debug {
+		panic("Doctype not found")
+	}
+	return Root{Error: newError(ErrElementNotFound, "doctype not found")}
+}
+
+// QuirksMode reports whether browsers render the document in quirks mode,
+// because it has no doctype or a doctype of a legacy version of HTML
+func (d Document) QuirksMode() bool {
+	if d.Pointer == nil || d.Pointer.Data == xmlDocument {
+		return false
+	}
+	var doctype *html.Node
+	for c := d.Pointer.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			doctype = c
+			break
+		}
+	}
+	if doctype == nil || doctype.Data != "html" {
+		return true
+	}
+	public, _ := Root{Pointer: doctype}.Attr("public")
+	public = strings.ToLower(public)
+	system, hasSystem := Root{Pointer: doctype}.Attr("system")
+	switch public {
+	case "-//w3o//dtd w3 html strict 3.0//en//", "-/w3c/dtd html 4.0 transitional/en", "html":
+		return true
+	}
+	if strings.EqualFold(system, "http://www.ibm.com/data/dtd/v11/ibmxhtml1-transitional.dtd") {
+		return true
+	}
+	for _, prefix := range quirksPublicIDs {
+		if strings.HasPrefix(public, prefix) {
+			return true
+		}
+	}
+	return !hasSystem && (strings.HasPrefix(public, "-//w3c//dtd html 4.01 frameset//") ||
+		strings.HasPrefix(public, "-//w3c//dtd html 4.01 transitional//"))
+}
+
+// Root returns a pointer to the root element of the document, the <html>
+// element of an HTML document, which is what HTMLParse returns
+func (d Document) Root() Root {
+	if d.Pointer != nil {
+		for c := d.Pointer.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				return Root{Pointer: c, NodeValue: c.Data}
+			}
+		}
+	}
+	if debug {
+		panic("Root element not found")
+	}
+	return Root{Error: newError(ErrElementNotFound, "root element not found")}
+}
+
+// Head returns a pointer to the <head> element of the document
+func (d Document) Head() Root {
+	return d.section("head")
+}
+
+// Body returns a pointer to the <body> element of the document, or to its
+// <frameset> element for documents made of frames
+func (d Document) Body() Root {
+	return d.section("body", "frameset")
+}
+
+// section returns the first child of the <html> element with one of the given names
+func (d Document) section(names ...string) Root {
+	if root := d.Root(); root.Error == nil && root.Pointer.Data == "html" {
+		for c := root.Pointer.FirstChild; c != nil; c = c.NextSibling {
+			for _, name := range names {
+				if c.Type == html.ElementNode && c.Data == name && c.Namespace == "" {
+					return Root{Pointer: c, NodeValue: c.Data}
+				}
+			}
+		}
+	}
+	if debug {
+		panic("Element `" + names[0] + "` not found")
+	}
+	return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("element `%s` not found", names[0]))}
+}
+
+// Title returns the text of the <title> element of the document with
+// whitespace collapsed, as browsers show it, or an empty string if it has none
+func (d Document) Title() string {
+	title, ok := matchOnce(d.Pointer, func(c *html.Node) bool {
+		return c.Data == "title" && c.Namespace == ""
+	})
+	if !ok {
+		return ""
+	}
+	return strings.Join(strings.FieldsFunc(Root{Pointer: title}.FullText(), func(c rune) bool {
+		return strings.ContainsRune(asciiSpace, c)
+	}), " ")
+}
+
+// Base returns the URL in the href of the <base> element of the
+// document, against which relative URLs are resolved, or nil if it has none
+func (d Document) Base() *netURL.URL {
+	if d.Pointer == nil {
+		return nil
+	}
+	return documentBase(d.Pointer)
+}
+
+// Lang returns the language of the document, as given by the lang
+// attribute of its root element, or an empty string if it has none
+func (d Document) Lang() string {
+	root := d.Root()
+	if root.Error != nil {
+		return ""
+	}
+	lang, _ := root.Attr("lang")
+	return strings.TrimSpace(lang)
+}
+
+// Encoding returns the name of the character encoding the document was
+// decoded from, as Root.Encoding does
+func (d Document) Encoding() string {
+	return Root{Pointer: d.Pointer}.Encoding()
+}
+
+// Children returns all top-level nodes of the document: the doctype,
+// comments and processing instructions along with the root element
+func (d Document) Children() []Root {
+	children := []Root{}
+	if d.Pointer == nil {
+		return children
+	}
+	for c := d.Pointer.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, Root{Pointer: c, NodeValue: c.Data})
+	}
+	return children
+}
+
+// HTML returns the HTML code for the whole document, including the doctype
+func (d Document) HTML() string {
+	if d.Pointer == nil {
+		return ""
+	}
+	return Root{Pointer: d.Pointer}.HTML()
+}
@@ -0,0 +1,113 @@
+package soup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+const documentPage = `<!DOCTYPE html>
+<!-- generated by hand -->
+<html lang="en-GB">
+<head>
+	<title>
+		A   page
+		about soup
+	</title>
+	<base href="https://example.com/docs/">
+</head>
+<body><p>Hello</p></body>
+</html>
+<!-- the end -->`
+
+func TestHTMLParseDocument(t *testing.T) {
+	doc := HTMLParseDocument(documentPage)
+	assert.NoError(t, doc.Error)
+	assert.Equal(t, html.DocumentNode, doc.Pointer.Type)
+
+	doctype := doc.Doctype()
+	assert.NoError(t, doctype.Error)
+	assert.Equal(t, "html", doctype.NodeValue)
+	assert.False(t, doc.QuirksMode())
+
+	assert.Equal(t, "html", doc.Root().NodeValue)
+	assert.Equal(t, HTMLParse(documentPage).HTML(), doc.Root().HTML())
+	assert.Equal(t, "head", doc.Head().NodeValue)
+	assert.Equal(t, "p", doc.Body().Find("p").NodeValue)
+	assert.Equal(t, "A page about soup", doc.Title())
+	assert.Equal(t, "https://example.com/docs/", doc.Base().String())
+	assert.Equal(t, "en-GB", doc.Lang())
+	assert.Equal(t, "", doc.Encoding())
+
+	var types []html.NodeType
+	for _, c := range doc.Children() {
+		types = append(types, c.Pointer.Type)
+	}
+	assert.Equal(t, []html.NodeType{html.DoctypeNode, html.CommentNode, html.ElementNode, html.CommentNode}, types)
+	assert.Equal(t, " generated by hand ", doc.Children()[1].NodeValue)
+	assert.Contains(t, doc.HTML(), "<!DOCTYPE html><!-- generated by hand --><html lang=\"en-GB\">")
+}
+
+func TestRootDocument(t *testing.T) {
+	p := HTMLParse(documentPage).Find("p")
+	doc := p.Document()
+	assert.NoError(t, doc.Error)
+	assert.Equal(t, "A page about soup", doc.Title())
+	assert.Equal(t, "html", doc.Doctype().NodeValue)
+
+	page := HTMLParseBytes([]byte(documentPage), "text/html; charset=utf-8")
+	assert.Equal(t, "utf-8", page.Document().Encoding())
+
+	feed := XMLParse(`<?xml version="1.0"?><feed xml:lang="fr"><title>Nouvelles</title></feed>`)
+	doc = feed.Document()
+	assert.Equal(t, "feed", doc.Root().NodeValue)
+	assert.Equal(t, "fr", doc.Lang())
+	assert.False(t, doc.QuirksMode())
+	assert.Equal(t, html.RawNode, doc.Children()[0].Pointer.Type)
+	assert.Equal(t, ErrElementNotFound, doc.Body().Error.(Error).Type)
+
+	assert.Equal(t, ErrNoDocument, NewTag("p", nil).Document().Error.(Error).Type)
+	assert.Equal(t, ErrNoDocument, Root{}.Document().Error.(Error).Type)
+}
+
+func TestDocumentMissingParts(t *testing.T) {
+	doc := HTMLParseDocument("<p>Hello</p>")
+	assert.Equal(t, ErrElementNotFound, doc.Doctype().Error.(Error).Type)
+	assert.True(t, doc.QuirksMode())
+	assert.Equal(t, "", doc.Title())
+	assert.Nil(t, doc.Base())
+	assert.Equal(t, "", doc.Lang())
+	// the parser always adds <head> and <body>
+	assert.NoError(t, doc.Head().Error)
+	assert.NoError(t, doc.Body().Error)
+
+	frames := HTMLParseDocument(`<!DOCTYPE html><frameset><frame src="a.html"></frameset>`)
+	assert.Equal(t, "frameset", frames.Body().NodeValue)
+
+	var empty Document
+	assert.Equal(t, ErrElementNotFound, empty.Root().Error.(Error).Type)
+	assert.Equal(t, ErrElementNotFound, empty.Head().Error.(Error).Type)
+	assert.Empty(t, empty.Children())
+	assert.Equal(t, "", empty.HTML())
+}
+
+func TestQuirksMode(t *testing.T) {
+	tests := []struct {
+		doctype string
+		quirks  bool
+	}{
+		{`<!DOCTYPE html>`, false},
+		{`<!doctype HTML>`, false},
+		{`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`, false},
+		{`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`, false},
+		{`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN">`, true},
+		{`<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 3.2 Final//EN">`, true},
+		{`<!DOCTYPE html PUBLIC "html">`, true},
+		{`<!DOCTYPE svg>`, true},
+		{``, true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.quirks, HTMLParseDocument(tt.doctype+"<p>Hello</p>").QuirksMode(), tt.doctype)
+	}
+}
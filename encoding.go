@@ -0,0 +1,295 @@
+package soup
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// EncodingSource tells where DetectEncoding found the encoding of a document
+type EncodingSource int
+
+const (
+	// EncodingFromBOM means the document starts with a byte order mark
+	EncodingFromBOM EncodingSource = iota
+	// EncodingFromHeader means the charset of the Content-Type header fits the document
+	EncodingFromHeader
+	// EncodingFromMeta means a <meta charset> or http-equiv declaration fits the document
+	EncodingFromMeta
+	// EncodingFromContent means the encoding was guessed from the bytes of
+	// the document, because it has no declaration or the declaration doesn't fit it
+	EncodingFromContent
+	// EncodingDefault means the document is plain ASCII and declares nothing, so it is taken to be UTF-8
+	EncodingDefault
+)
+
+// EncodingResult is the character encoding found by DetectEncoding
+type EncodingResult struct {
+	// Encoding is the name of the encoding the document is written in, as
+	// given by the WHATWG Encoding Standard, such as "utf-8" or "euc-kr"
+	Encoding string
+	// Declared is the name of the encoding the Content-Type header or, when
+	// the header has none, the document itself claims. It is empty when
+	// there is no such declaration, and differs from Encoding when the
+	// declaration is wrong
+	Declared string
+	// Source tells where Encoding comes from
+	Source EncodingSource
+	// Confidence goes from 0 to 1, and is 1 when there is no doubt about Encoding
+	Confidence float64
+}
+
+// detectLen is how much of a document DetectEncoding looks at
+const detectLen = 64 << 10
+
+// sniffLen is how much of a document is searched for a <meta> declaration of its encoding
+const sniffLen = 1024
+
+// sniffedEncodings are the encodings DetectEncoding can tell apart by
+// looking at the text, the most likely ones first
+var sniffedEncodings = []string{"utf-8", "shift_jis", "euc-jp", "euc-kr", "gb18030", "big5", "windows-1252"}
+
+// Some of the most frequently used Chinese characters, in simplified
+// and traditional form, which tell GB18030 and Big5 text apart from
+// text decoded with the wrong encoding
+const (
+	commonSimplified  = "的一是不了在人有我他这个们中来上大为和国地到以说时要就出会可也你对生能而子那得于着下自之年过发后作里用道行所然家种事成方多经么去法学如都同现当没动面起看定天分还进好小部其些主样理心她本前开但因只从想实日军者意无力它与长把机十民第公此已工使情明性知全三又关点正业外将两高间由问很最重并物手应战向头文体政美相见被利什二等产或新己制身果加西斯月话合回特代内信表化老给世位次度门任常先海通教儿原东声提立及比员解水名真论处走义各入几口认条平系气题活尔更别打女变四神总何电数安少报才结反受目太量再感建务做接必场件计管期市直德资命山金指克许统区保至队形社便空决治展马科司五基眼书非则听白却界达光放强即像难且权思王象完设式色路记南品住告类求据程北边死张该交规万取拉格望觉术领共确传师观清今切院让识候带导争运笑飞风步改收根干造言联持组每济车亲极林服快办议往元英士证近失转夫令准布始怎呢存未远叫台单影具罗字爱击流备兵连调深商算质团集百需价花党华城石级整府离况亚请技际约示复病息究线似官火断精满支视消越器容照须九增研写称企八功吗包片史委乎查轻易早曾除农找装广显吧阿李标谈吃图念六引历首医局突专费号尽另周较注语仅考落青随选列武红响虽推势参希古众构房半节土投某案黑维革划敌致陈律足态护七兴派孩验责营星够章音跟志底站严巴例防族供效续施留讲型料终答紧黄绝奇察母京段依批群项故按河米围江织害斗双境客纪采举杀攻父苏密低朝友诉止细愿千值仍男钱破网热助倒育属坐帝限船脸职速刻乐否刚威毛状率甚独球般普怕弹校苦创假久错承印晚兰试股拿脑预谁益阳若哪微尼继送急血惊伤素药适波夜省初喜卫源食险待述陆习置居劳财环排福纳欢雷警获模充负云停木游龙树疑层冷洲冲射略范竟句室异激汉村哈策演简卡罪判担州静退既衣您宗积余痛检差富灵协角占配征修皮挥胜降阶审沉坚善妈刘读啊超免压银买皇养伊怀执副乱抗犯追帮宣佛岁航优怪香著田铁控税左右份穷"
+	commonTraditional = "的一是不了在人有我他這個們中來上大為和國地到以說時要就出會可也你對生能而子那得於著下自之年過發後作裡用道行所然家種事成方多經麼去法學如都同現當沒動面起看定天分還進好小部其些主樣理心她本前開但因只從想實日軍者意無力它與長把機十民第公此已工使情明性知全三又關點正業外將兩高間由問很最重並物手應戰向頭文體政美相見被利什二等產或新己制身果加西斯月話合回特代內信表化老給世位次度門任常先海通教兒原東聲提立及比員解水名真論處走義各入幾口認條平系氣題活爾更別打女變四神總何電數安少報才結反受目太量再感建務做接必場件計管期市直德資命山金指克許統區保至隊形社便空決治展馬科司五基眼書非則聽白卻界達光放強即像難且權思王象完設式色路記南品住告類求據程北邊死張該交規萬取拉格望覺術領共確傳師觀清今切院讓識候帶導爭運笑飛風步改收根乾造言聯持組每濟車親極林服快辦議往元英士證近失轉夫令準布始怎呢存未遠叫台單影具羅字愛擊流備兵連調深商算質團集百需價花黨華城石級整府離況亞請技際約示復病息究線似官火斷精滿支視消越器容照須九增研寫稱企八功嗎包片史委乎查輕易早曾除農找裝廣顯吧阿李標談吃圖念六引歷首醫局突專費號盡另周較注語僅考落青隨選列武紅響雖推勢參希古眾構房半節土投某案黑維革劃敵致陳律足態護七興派孩驗責營星夠章音跟志底站嚴巴例防族供效續施留講型料終答緊黃絕奇察母京段依批群項故按河米圍江織害鬥雙境客紀採舉殺攻父蘇密低朝友訴止細願千值仍男錢破網熱助倒育屬坐帝限船臉職速刻樂否剛威毛狀率甚獨球般普怕彈校苦創假久錯承印晚蘭試股拿腦預誰益陽若哪微尼繼送急血驚傷素藥適波夜省初喜衛源食險待述陸習置居勞財環排福納歡雷警獲模充負雲停木遊龍樹疑層冷洲衝射略範竟句室異激漢村哈策演簡卡罪判擔州靜退既衣您宗積餘痛檢差富靈協角佔配征修皮揮勝降階審沉堅善媽劉讀啊超免壓銀買皇養伊懷執副亂抗犯追幫宣佛歲航優怪香著田鐵控稅左右份窮"
+)
+
+// The characters of commonSimplified and commonTraditional, for looking them up quickly
+var commonSimplifiedSet, commonTraditionalSet = runeSet(commonSimplified), runeSet(commonTraditional)
+
+func runeSet(s string) map[rune]struct{} {
+	set := make(map[rune]struct{}, utf8.RuneCountInString(s))
+	for _, r := range s {
+		set[r] = struct{}{}
+	}
+	return set
+}
+
+// DetectEncoding works out the character encoding of an HTML document,
+// like UnicodeDammit does for BeautifulSoup. A byte order mark settles
+// it. Otherwise the charset of headerContentType, or failing that a
+// <meta> declaration in the document, is weighed against how plausible
+// the text is when decoded with each of the common encodings, so that a
+// wrong declaration doesn't garble the text. UTF-8, Shift_JIS, EUC-JP,
+// EUC-KR, GB18030, Big5 and windows-1252 can be recognized without any declaration
+func DetectEncoding(content []byte, headerContentType string) EncodingResult {
+	var res EncodingResult
+	declaredBy := EncodingFromMeta
+	if _, params, err := mime.ParseMediaType(headerContentType); err == nil {
+		if _, name := charset.Lookup(params["charset"]); name != "" {
+			res.Declared, declaredBy = name, EncodingFromHeader
+		}
+	}
+	if res.Declared == "" {
+		res.Declared = metaEncoding(content)
+	}
+
+	if _, name, certain := charset.DetermineEncoding(content, ""); certain {
+		res.Encoding, res.Source, res.Confidence = name, EncodingFromBOM, 1
+		return res
+	}
+
+	truncated := len(content) > detectLen
+	if truncated {
+		content = content[:detectLen]
+	}
+	if !hasHighBit(content) {
+		res.Encoding, res.Source, res.Confidence = "utf-8", EncodingDefault, 1
+		if res.Declared != "" {
+			res.Encoding, res.Source = res.Declared, declaredBy
+		}
+		return res
+	}
+
+	res.Source = EncodingFromContent
+	candidates := sniffedEncodings
+	if res.Declared != "" {
+		candidates = append([]string{res.Declared}, candidates...)
+	}
+	for i, name := range candidates {
+		if i > 0 && name == res.Declared {
+			continue
+		}
+		score := plausibility(content, name, truncated)
+		if name == res.Declared && score > 0 {
+			// a declaration which fits the text is trusted over a guess
+			score = min(1, score+0.15)
+		}
+		if score > res.Confidence {
+			res.Encoding, res.Confidence = name, score
+			if name == res.Declared {
+				res.Source = declaredBy
+			} else {
+				res.Source = EncodingFromContent
+			}
+		}
+	}
+	if res.Encoding == "" {
+		res.Encoding = "windows-1252"
+	}
+	return res
+}
+
+// metaEncoding returns the encoding declared by a <meta> tag in the first
+// kilobyte of content, following the prescan algorithm of the HTML spec
+func metaEncoding(content []byte) string {
+	if len(content) > sniffLen {
+		content = content[:sniffLen]
+	}
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data != "meta" {
+				continue
+			}
+			label, pragma := "", false
+			for _, attr := range tok.Attr {
+				switch attr.Key {
+				case "charset":
+					label, pragma = attr.Val, true
+				case "http-equiv":
+					pragma = pragma || strings.EqualFold(attr.Val, "content-type")
+				case "content":
+					if _, params, err := mime.ParseMediaType(attr.Val); err == nil && label == "" {
+						label = params["charset"]
+					}
+				}
+			}
+			if _, name := charset.Lookup(label); name != "" && pragma {
+				if strings.HasPrefix(name, "utf-16") {
+					// a document that can be read this far isn't UTF-16
+					return "utf-8"
+				}
+				return name
+			}
+		}
+	}
+}
+
+// lookupEncoding finds the encoding with the given label, such as "Shift_JIS" or "latin1"
+func lookupEncoding(label string) (encoding.Encoding, string, error) {
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		if debug {
+			panic("Unknown encoding " + label)
+		}
+		return nil, "", newError(ErrUnknownEncoding, fmt.Sprintf("unknown encoding %q", label))
+	}
+	return enc, name, nil
+}
+
+func hasHighBit(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// decodePrefix decodes content with enc. When content is truncated, a
+// character cut in half at its end is left out
+func decodePrefix(enc encoding.Encoding, content []byte, truncated bool) ([]byte, error) {
+	if !truncated {
+		return enc.NewDecoder().Bytes(content)
+	}
+	dec := enc.NewDecoder()
+	var decoded bytes.Buffer
+	buf := make([]byte, 4096)
+	for {
+		nDst, nSrc, err := dec.Transform(buf, content, false)
+		decoded.Write(buf[:nDst])
+		content = content[nSrc:]
+		switch err {
+		case transform.ErrShortDst:
+		case nil, transform.ErrShortSrc:
+			return decoded.Bytes(), nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// plausibility returns how much content decoded with the given encoding
+// looks like real text, from 0 to 1, judging by its non-ASCII characters.
+// When content is truncated, it can end in the middle of a character
+func plausibility(content []byte, name string, truncated bool) float64 {
+	enc, _ := charset.Lookup(name)
+	if enc == nil {
+		return 0
+	}
+	decoded, err := decodePrefix(enc, content, truncated)
+	if err != nil {
+		return 0
+	}
+	var total, bad, hangul, kana, han, common, punct, latin, symbol, letter float64
+	commonSet := commonSimplifiedSet
+	if name == "big5" {
+		commonSet = commonTraditionalSet
+	}
+	for _, r := range string(decoded) {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		total++
+		switch {
+		case r == utf8.RuneError:
+			bad++
+		case r >= 0xac00 && r <= 0xd7a3, r >= 0x3130 && r <= 0x318f:
+			hangul++
+		case r >= 0x3040 && r <= 0x30ff:
+			kana++
+		case r >= 0x4e00 && r <= 0x9fff:
+			han++
+			if _, ok := commonSet[r]; ok {
+				common++
+			}
+		case r >= 0x3000 && r <= 0x303f, r >= 0xff01 && r <= 0xff5e:
+			punct++
+		case r >= 0xc0 && r <= 0xff && r != 0xd7 && r != 0xf7, r >= 0x2013 && r <= 0x2026, r == 0x20ac,
+			r == 0x152, r == 0x153, r == 0x160, r == 0x161, r == 0x178, r == 0x17d, r == 0x17e:
+			latin++
+		case r >= 0xa0 && r <= 0xbf:
+			symbol++
+		case unicode.IsLetter(r):
+			letter++
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	var score float64
+	switch name {
+	case "utf-8":
+		score = 1
+	case "shift_jis", "euc-jp", "iso-2022-jp":
+		// Japanese is mostly written with kana, which Chinese and Korean text decoded this way lacks
+		score = (kana + han + punct) / total * min(1, 0.2+4*kana/total)
+	case "euc-kr":
+		score = (hangul + punct) / total
+	case "gb18030", "gbk", "big5":
+		score = (common + 0.3*(han-common) + punct) / total
+	case "windows-1252":
+		// any bytes decode in windows-1252, so it only wins when nothing else fits
+		score = 0.8 * (latin + 0.5*symbol) / total
+	default:
+		score = (latin + 0.5*symbol + hangul + kana + han + punct + letter) / total
+	}
+	// a few bad bytes can be a mistake, many mean the encoding is wrong
+	return max(0, score*(1-10*bad/total))
+}
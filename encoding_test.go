@@ -0,0 +1,160 @@
+package soup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	koreanText   = "두 정수 A와 B를 입력받은 다음, A+B를 출력하는 프로그램을 작성하시오. 첫째 줄에 A와 B가 주어진다."
+	japaneseText = "吾輩は猫である。名前はまだ無い。どこで生れたかとんと見当がつかぬ。何でも薄暗いじめじめした所でニャーニャー泣いていた事だけは記憶している。"
+	chineseText  = "中华人民共和国是工人阶级领导的、以工农联盟为基础的人民民主专政的社会主义国家。我们的生活越来越好。"
+	taiwanText   = "中華民國是一個民主共和國，我們的生活越來越好，這是大家都知道的事情。"
+	frenchText   = "Le cœur a ses raisons que la raison ne connaît point. Être ou ne pas être, voilà la question — déjà vu."
+)
+
+func encode(t *testing.T, s, label string) []byte {
+	enc, _ := charset.Lookup(label)
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	assert.NoError(t, err)
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		text, encoding string
+	}{
+		{koreanText, "euc-kr"},
+		{japaneseText, "shift_jis"},
+		{japaneseText, "euc-jp"},
+		{chineseText, "gb18030"},
+		{taiwanText, "big5"},
+		{frenchText, "windows-1252"},
+		{frenchText, "utf-8"},
+	}
+	for _, tt := range tests {
+		content := encode(t, "<p>"+tt.text+"</p>", tt.encoding)
+
+		res := DetectEncoding(content, "")
+		assert.Equal(t, tt.encoding, res.Encoding)
+		assert.Equal(t, "", res.Declared)
+		assert.Equal(t, EncodingFromContent, res.Source)
+		assert.Greater(t, res.Confidence, 0.5)
+
+		// a header which fits the text is trusted
+		res = DetectEncoding(content, "text/html; charset="+tt.encoding)
+		assert.Equal(t, tt.encoding, res.Encoding)
+		assert.Equal(t, tt.encoding, res.Declared)
+		assert.Equal(t, EncodingFromHeader, res.Source)
+		assert.Greater(t, res.Confidence, 0.9)
+	}
+}
+
+func TestDetectEncodingWrongDeclaration(t *testing.T) {
+	// the header lies about the encoding
+	content := encode(t, "<p>"+koreanText+"</p>", "euc-kr")
+	res := DetectEncoding(content, "text/html; charset=utf-8")
+	assert.Equal(t, "euc-kr", res.Encoding)
+	assert.Equal(t, "utf-8", res.Declared)
+	assert.Equal(t, EncodingFromContent, res.Source)
+
+	res = DetectEncoding(content, "text/html; charset=ISO-8859-1")
+	assert.Equal(t, "euc-kr", res.Encoding)
+	assert.Equal(t, "windows-1252", res.Declared)
+
+	// and so does the document
+	content = encode(t, `<meta charset="utf-8"><p>`+japaneseText+"</p>", "shift_jis")
+	res = DetectEncoding(content, "")
+	assert.Equal(t, "shift_jis", res.Encoding)
+	assert.Equal(t, "utf-8", res.Declared)
+	assert.Equal(t, EncodingFromContent, res.Source)
+}
+
+func TestDetectEncodingDeclared(t *testing.T) {
+	content := encode(t, `<meta http-equiv="Content-Type" content="text/html; charset=euc-kr"><p>`+koreanText+"</p>", "euc-kr")
+	res := DetectEncoding(content, "")
+	assert.Equal(t, EncodingResult{Encoding: "euc-kr", Declared: "euc-kr", Source: EncodingFromMeta, Confidence: 1}, res)
+
+	// the header is looked at before the document
+	res = DetectEncoding(content, "text/html; charset=euc-kr")
+	assert.Equal(t, EncodingFromHeader, res.Source)
+
+	// encodings which aren't sniffed are taken from the declaration
+	content = encode(t, "<p>Все счастливые семьи похожи друг на друга</p>", "windows-1251")
+	res = DetectEncoding(content, "text/html; charset=windows-1251")
+	assert.Equal(t, "windows-1251", res.Encoding)
+	assert.Equal(t, EncodingFromHeader, res.Source)
+
+	// plain ASCII is whatever it is declared to be
+	res = DetectEncoding([]byte(`<meta charset="shift_jis"><p>hello</p>`), "")
+	assert.Equal(t, EncodingResult{Encoding: "shift_jis", Declared: "shift_jis", Source: EncodingFromMeta, Confidence: 1}, res)
+	res = DetectEncoding([]byte(`<p>hello</p>`), "text/html")
+	assert.Equal(t, EncodingResult{Encoding: "utf-8", Source: EncodingDefault, Confidence: 1}, res)
+}
+
+func TestDetectEncodingBOM(t *testing.T) {
+	utf16, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("<p>café</p>"))
+	res := DetectEncoding(utf16, "text/html; charset=euc-kr")
+	assert.Equal(t, EncodingResult{Encoding: "utf-16be", Declared: "euc-kr", Source: EncodingFromBOM, Confidence: 1}, res)
+
+	res = DetectEncoding([]byte("\xef\xbb\xbf<p>café</p>"), "")
+	assert.Equal(t, EncodingResult{Encoding: "utf-8", Source: EncodingFromBOM, Confidence: 1}, res)
+}
+
+func TestDetectEncodingLongDocument(t *testing.T) {
+	// the document is cut in the middle of a character
+	content := encode(t, "<p>"+strings.Repeat(koreanText, 1000)+"</p>", "euc-kr")
+	res := DetectEncoding(content[:detectLen+1], "")
+	assert.Equal(t, "euc-kr", res.Encoding)
+	assert.Equal(t, 1.0, res.Confidence)
+
+	// the text after the last ASCII byte is looked at as well
+	content = encode(t, "<p>"+strings.Repeat(japaneseText, 1000)+"</p>", "shift_jis")
+	assert.Equal(t, "shift_jis", DetectEncoding(content, "").Encoding)
+	content = encode(t, "<p>"+strings.Repeat(chineseText, 1000)+"</p>", "gb18030")
+	assert.Equal(t, "gb18030", DetectEncoding(content[:detectLen+1], "").Encoding)
+}
+
+func TestHTMLParseReaderEncoding(t *testing.T) {
+	content := encode(t, "<p>"+koreanText+"</p>", "euc-kr")
+	page := HTMLParseBytes(content, "text/html; charset=utf-8")
+	assert.NoError(t, page.Error)
+	assert.Equal(t, "euc-kr", page.Encoding())
+	assert.Equal(t, koreanText, page.Find("p").Text())
+
+	page = HTMLParseReader(strings.NewReader("<p>caf\xe9</p>"), ParseOptions{Encoding: "ISO-8859-15"})
+	assert.Equal(t, "iso-8859-15", page.Encoding())
+	assert.Equal(t, "café", page.Find("p").Text())
+
+	page = HTMLParseReader(strings.NewReader("<p>café</p>"), ParseOptions{Encoding: "klingon"})
+	assert.Equal(t, ErrUnknownEncoding, page.Error.(Error).Type)
+}
+
+func TestGetEncoding(t *testing.T) {
+	content := encode(t, "<p>"+koreanText+"</p>", "euc-kr")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	body, err := Get(ts.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>"+koreanText+"</p>", body)
+
+	defer func() { ForceEncoding = "" }()
+	ForceEncoding = "windows-1252"
+	body, err = Get(ts.URL)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "<p>"+koreanText+"</p>", body)
+
+	ForceEncoding = "klingon"
+	_, err = Get(ts.URL)
+	assert.Equal(t, ErrUnknownEncoding, err.(Error).Type)
+}
@@ -5,11 +5,11 @@ go 1.26.5
 require (
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/net v0.56.0
+	golang.org/x/text v0.39.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/text v0.39.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,90 @@
+package soup
+
+import (
+	"iter"
+
+	"golang.org/x/net/html"
+)
+
+// Descendants returns an iterator over all nodes below the pointer in
+// document order, including text and comment nodes. Nothing is collected
+// up front, so breaking out of the loop early stops the traversal
+func (r Root) Descendants() iter.Seq[Root] {
+	return func(yield func(Root) bool) {
+		if r.Pointer != nil {
+			walkDescendants(r.Pointer, func(n *html.Node) bool {
+				return !yield(Root{Pointer: n, NodeValue: n.Data})
+			})
+		}
+	}
+}
+
+// Ancestors returns an iterator over the elements containing the pointer,
+// starting with its parent
+func (r Root) Ancestors() iter.Seq[Root] {
+	return func(yield func(Root) bool) {
+		if r.Pointer == nil {
+			return
+		}
+		for p := parentElement(r.Pointer); p != nil; p = parentElement(p) {
+			if !yield(Root{Pointer: p, NodeValue: p.Data}) {
+				return
+			}
+		}
+	}
+}
+
+// NextSiblings returns an iterator over the nodes following the pointer
+// that share its parent, including text and comment nodes
+func (r Root) NextSiblings() iter.Seq[Root] {
+	return func(yield func(Root) bool) {
+		if r.Pointer == nil {
+			return
+		}
+		for s := r.Pointer.NextSibling; s != nil; s = s.NextSibling {
+			if !yield(Root{Pointer: s, NodeValue: s.Data}) {
+				return
+			}
+		}
+	}
+}
+
+// PrevSiblings returns an iterator over the nodes preceding the pointer
+// that share its parent, starting with the closest one
+func (r Root) PrevSiblings() iter.Seq[Root] {
+	return func(yield func(Root) bool) {
+		if r.Pointer == nil {
+			return
+		}
+		for s := r.Pointer.PrevSibling; s != nil; s = s.PrevSibling {
+			if !yield(Root{Pointer: s, NodeValue: s.Data}) {
+				return
+			}
+		}
+	}
+}
+
+// Strings returns an iterator over the text nodes below the pointer in document order
+func (r Root) Strings() iter.Seq[Root] {
+	return func(yield func(Root) bool) {
+		for d := range r.Descendants() {
+			if d.Pointer.Type == html.TextNode && !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// FindAllSeq is like FindAll, but returns an iterator finding
+// the matching elements one at a time as the loop asks for them.
+// Like FindAll, it panics when a key is missing its value
+func (r Root) FindAllSeq(args ...string) iter.Seq[Root] {
+	mustFindArgs(args)
+	return func(yield func(Root) bool) {
+		for d := range r.Descendants() {
+			if matchArgs(d.Pointer, args, false) && !yield(d) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package soup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestDescendants(t *testing.T) {
+	var names []string
+	for d := range doc.Find("ul").Descendants() {
+		if d.Pointer.Type == html.ElementNode {
+			names = append(names, d.NodeValue)
+		}
+	}
+	assert.Equal(t, []string{"li", "a", "li", "a"}, names)
+
+	count := 0
+	for range doc.Descendants() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestAncestorsAndSiblings(t *testing.T) {
+	var names []string
+	for a := range doc.Find("a").Ancestors() {
+		names = append(names, a.NodeValue)
+	}
+	assert.Equal(t, []string{"li", "ul", "body", "html"}, names)
+
+	var ids []string
+	for s := range doc.Find("div", "id", "2").NextSiblings() {
+		if id, ok := s.Attrs()["id"]; ok {
+			ids = append(ids, id)
+		}
+	}
+	assert.Equal(t, []string{"3", "5"}, ids)
+
+	var prev []string
+	for s := range doc.Find("div", "id", "2").PrevSiblings() {
+		if text := strings.TrimSpace(s.NodeValue); text != "" {
+			prev = append(prev, text)
+		}
+		if s.NodeValue == "table" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"check", "div", "table"}, prev)
+}
+
+func TestStrings(t *testing.T) {
+	var texts []string
+	for s := range doc.Find("ul").Find("li").Strings() {
+		texts = append(texts, s.NodeValue)
+	}
+	assert.Equal(t, []string{"To a ", "JSP page", " right?"}, texts)
+}
+
+func TestFindAllSeq(t *testing.T) {
+	var ids []string
+	for div := range doc.FindAllSeq("div") {
+		ids = append(ids, div.Attrs()["id"])
+		if len(ids) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"0", "1"}, ids)
+
+	count := 0
+	for range multipleClasses.FindAllSeq("div", "class", "first", "class", "second") {
+		count++
+	}
+	assert.Equal(t, 4, count)
+
+	assert.Panics(t, func() { doc.FindAllSeq("div", "id") })
+	for range doc.Find("missing").FindAllSeq("div") {
+		t.Fatal("missing root should yield nothing")
+	}
+}
@@ -0,0 +1,462 @@
+package soup
+
+import (
+	"fmt"
+	netURL "net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// LinkStyle decides how Markdown writes out links
+type LinkStyle int
+
+const (
+	// LinkInline writes links as [text](url)
+	LinkInline LinkStyle = iota
+	// LinkReference writes links as [text][1], with the URLs listed
+	// at the end of the document as [1]: url
+	LinkReference
+)
+
+// MarkdownOptions controls how Markdown converts HTML
+type MarkdownOptions struct {
+	// LinkStyle decides how links are written out
+	LinkStyle LinkStyle
+	// BaseURL is the URL of the page. When set, relative URLs of links
+	// and images are resolved against it, after taking the <base> of the
+	// document into account
+	BaseURL *netURL.URL
+}
+
+// mdSkipped elements have nothing to show in Markdown
+var mdSkipped = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+}
+
+// mdEscaper escapes the characters which have a meaning in inline Markdown
+var mdEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`)
+
+// mdBlock is a block of Markdown such as a paragraph or a list
+type mdBlock struct {
+	text string
+	list bool
+}
+
+// markdown converts a DOM to Markdown, collecting reference links along the way
+type markdown struct {
+	opts MarkdownOptions
+	base *netURL.URL
+	refs []string
+	ids  map[string]int
+}
+
+// blocks converts nodes, which share a parent, to Markdown blocks,
+// gathering inline content between block elements into paragraphs
+func (m *markdown) blocks(nodes []*html.Node) []mdBlock {
+	var out []mdBlock
+	var para strings.Builder
+	flush := func() {
+		if p := mdParagraph(para.String()); p != "" {
+			out = append(out, mdBlock{text: p})
+		}
+		para.Reset()
+	}
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && mdIsBlock(n) {
+			flush()
+			out = append(out, m.block(n)...)
+		} else {
+			para.WriteString(m.inline(n))
+		}
+	}
+	flush()
+	return out
+}
+
+// block converts the block element n
+func (m *markdown) block(n *html.Node) []mdBlock {
+	if mdSkipped[n.Data] {
+		return nil
+	}
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := mdLine(m.inlineChildren(n))
+		if text == "" {
+			return nil
+		}
+		return []mdBlock{{text: strings.Repeat("#", int(n.Data[1]-'0')) + " " + text}}
+	case "ul", "ol":
+		return m.list(n)
+	case "pre":
+		return []mdBlock{{text: mdCodeBlock(n)}}
+	case "blockquote":
+		inner := joinBlocks(m.blocks(childNodes(n)))
+		if inner == "" {
+			return nil
+		}
+		lines := strings.Split(inner, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return []mdBlock{{text: strings.Join(lines, "\n")}}
+	case "hr":
+		return []mdBlock{{text: "---"}}
+	case "table":
+		if table := m.table(n); table != "" {
+			return []mdBlock{{text: table}}
+		}
+		return nil
+	}
+	return m.blocks(childNodes(n))
+}
+
+// list converts the <ul> or <ol> element n, indenting the content of
+// each item so that it lines up with the text after the marker
+func (m *markdown) list(n *html.Node) []mdBlock {
+	num := 1
+	if start, ok := (Root{Pointer: n}).Attr("start"); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(start)); err == nil {
+			num = i
+		}
+	}
+	var items []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if n.Data == "ol" {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		var item strings.Builder
+		for i, b := range m.blocks(childNodes(c)) {
+			if i > 0 {
+				if b.list {
+					item.WriteString("\n")
+				} else {
+					item.WriteString("\n\n")
+				}
+			}
+			item.WriteString(b.text)
+		}
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(item.String(), "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = indent + lines[i]
+			}
+		}
+		items = append(items, strings.TrimRight(marker+strings.Join(lines, "\n"), " "))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return []mdBlock{{text: strings.Join(items, "\n"), list: true}}
+}
+
+// table converts the <table> element n to a GitHub-flavored Markdown
+// table, using its first row as the header. Tables nested in its cells
+// are reduced to their text
+func (m *markdown) table(n *html.Node) string {
+	var rows [][]string
+	columns := 0
+	for _, tr := range tableRows(n) {
+		var row []string
+		for cell := tr.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+				text := strings.ReplaceAll(mdLine(m.inlineChildren(cell)), "|", `\|`)
+				row = append(row, text)
+			}
+		}
+		rows = append(rows, row)
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	for i, row := range rows {
+		buf.WriteString("|")
+		for j := 0; j < columns; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = row[j]
+			}
+			buf.WriteString(" " + cell + " |")
+		}
+		if i == 0 {
+			buf.WriteString("\n|" + strings.Repeat(" --- |", columns))
+		}
+		if i < len(rows)-1 {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+// tableRows returns the rows of the <table> element n, leaving out those
+// of tables nested inside it
+func tableRows(n *html.Node) []*html.Node {
+	var rows []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.Data {
+		case "tr":
+			rows = append(rows, c)
+		case "thead", "tbody", "tfoot":
+			for tr := c.FirstChild; tr != nil; tr = tr.NextSibling {
+				if tr.Type == html.ElementNode && tr.Data == "tr" {
+					rows = append(rows, tr)
+				}
+			}
+		}
+	}
+	return rows
+}
+
+// inline converts n as part of a paragraph. Line breaks are kept as
+// newlines, all other whitespace is collapsed when the paragraph is done
+func (m *markdown) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return mdEscaper.Replace(spaceReplacer.Replace(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	switch n.Data {
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return mdWrap("**", "**", m.inlineChildren(n))
+	case "em", "i":
+		return mdWrap("*", "*", m.inlineChildren(n))
+	case "del", "s", "strike":
+		return mdWrap("~~", "~~", m.inlineChildren(n))
+	case "code", "kbd", "samp", "tt":
+		return mdCodeSpan(Root{Pointer: n}.NormalizedText())
+	case "td", "th":
+		// cells of a table inside a table cell
+		return " " + m.inlineChildren(n) + " "
+	case "a":
+		return m.link(n)
+	case "img":
+		return m.image(n)
+	}
+	if mdSkipped[n.Data] {
+		return ""
+	}
+	return m.inlineChildren(n)
+}
+
+func (m *markdown) inlineChildren(n *html.Node) string {
+	var buf strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		buf.WriteString(m.inline(c))
+	}
+	return buf.String()
+}
+
+// link converts the <a> element n, leaving only its text when it has no href
+func (m *markdown) link(n *html.Node) string {
+	text := m.inlineChildren(n)
+	href, ok := (Root{Pointer: n}).Attr("href")
+	if !ok || strings.Trim(text, asciiSpace) == "" {
+		return text
+	}
+	dest := m.destination(href, n)
+	if m.opts.LinkStyle == LinkReference {
+		id, ok := m.ids[dest]
+		if !ok {
+			m.refs = append(m.refs, dest)
+			id = len(m.refs)
+			m.ids[dest] = id
+		}
+		return mdWrap("[", fmt.Sprintf("][%d]", id), text)
+	}
+	return mdWrap("[", "]("+dest+")", text)
+}
+
+// image converts the <img> element n
+func (m *markdown) image(n *html.Node) string {
+	src, ok := (Root{Pointer: n}).Attr("src")
+	if !ok {
+		return ""
+	}
+	alt, _ := (Root{Pointer: n}).Attr("alt")
+	return "![" + mdEscaper.Replace(normalizeSpace(alt)) + "](" + m.destination(src, n) + ")"
+}
+
+// destination resolves url and adds the title of n, ready to go between
+// the parentheses of a link or image
+func (m *markdown) destination(url string, n *html.Node) string {
+	url = strings.TrimSpace(url)
+	if u, err := netURL.Parse(url); err == nil && m.base != nil {
+		url = m.base.ResolveReference(u).String()
+	}
+	if strings.ContainsAny(url, " ()<>") {
+		url = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	if title, ok := (Root{Pointer: n}).Attr("title"); ok && title != "" {
+		url += ` "` + strings.ReplaceAll(normalizeSpace(title), `"`, `\"`) + `"`
+	}
+	return url
+}
+
+// mdIsBlock reports whether the element n is converted to blocks of its own
+func mdIsBlock(n *html.Node) bool {
+	switch n.Data {
+	case "html", "body", "li", "td", "th":
+		return true
+	}
+	return blockElements[n.Data] || mdSkipped[n.Data]
+}
+
+// mdParagraph cleans up the whitespace of inline Markdown, keeping line breaks
+func mdParagraph(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = normalizeSpace(line); line != "" {
+			kept = append(kept, mdEscapeLineStart(line))
+		}
+	}
+	return strings.Join(kept, "  \n")
+}
+
+// mdEscapeLineStart escapes what would make line a heading, blockquote,
+// list item or setext underline instead of text
+func mdEscapeLineStart(line string) string {
+	switch line[0] {
+	case '#', '>', '-', '+', '=':
+		return `\` + line
+	}
+	digits := 0
+	for digits < len(line) && line[digits] >= '0' && line[digits] <= '9' {
+		digits++
+	}
+	if digits > 0 && digits < len(line) && (line[digits] == '.' || line[digits] == ')') {
+		return line[:digits] + `\` + line[digits:]
+	}
+	return line
+}
+
+// mdLine is like mdParagraph, but for places where line breaks can't go
+func mdLine(s string) string {
+	return normalizeSpace(strings.ReplaceAll(s, "\n", " "))
+}
+
+// mdWrap puts s between open and close, keeping the whitespace around s
+// outside of them, as Markdown doesn't allow emphasis to start or end
+// with a space. Empty s is left alone
+func mdWrap(open, close, s string) string {
+	trimmed := strings.Trim(s, asciiSpace)
+	if trimmed == "" {
+		return s
+	}
+	start := strings.Index(s, trimmed)
+	return s[:start] + open + trimmed + close + s[start+len(trimmed):]
+}
+
+// mdCodeSpan wraps s in enough backticks that none of those in s close it
+func mdCodeSpan(s string) string {
+	if s == "" {
+		return ""
+	}
+	fence := strings.Repeat("`", longestRun(s, '`')+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+// mdCodeBlock converts the <pre> element n to a fenced code block, taking
+// the language from a language-* class on it or on the <code> inside it
+func mdCodeBlock(n *html.Node) string {
+	pre := Root{Pointer: n}
+	lang := ""
+	for _, r := range []Root{pre, {Pointer: firstElementChild(n)}} {
+		for _, class := range r.Classes() {
+			if l, ok := strings.CutPrefix(class, "language-"); ok && lang == "" {
+				lang = l
+			}
+		}
+	}
+	code := strings.TrimSuffix(pre.GetText(TextOptions{}), "\n")
+	fence := strings.Repeat("`", max(3, longestRun(code, '`')+1))
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+// longestRun returns the length of the longest run of c in s
+func longestRun(s string, c byte) int {
+	longest, run := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func firstElementChild(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+func childNodes(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+func joinBlocks(blocks []mdBlock) string {
+	texts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		texts = append(texts, b.text)
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// Markdown converts the pointer and everything below it to GitHub-flavored
+// Markdown. Headings, paragraphs, nested lists, links, images, emphasis,
+// code, blockquotes and tables are converted, other elements are reduced
+// to their text, and scripts and styles are left out
+func (r Root) Markdown(opts MarkdownOptions) string {
+	if r.Pointer == nil {
+		return ""
+	}
+	m := &markdown{opts: opts, base: opts.BaseURL, ids: map[string]int{}}
+	if base := documentBase(r.Pointer); base != nil {
+		if m.base != nil {
+			base = m.base.ResolveReference(base)
+		}
+		m.base = base
+	}
+	nodes := []*html.Node{r.Pointer}
+	if r.Pointer.Type == html.DocumentNode {
+		nodes = childNodes(r.Pointer)
+	}
+	out := joinBlocks(m.blocks(nodes))
+	if len(m.refs) > 0 {
+		out += "\n\n"
+		for i, ref := range m.refs {
+			out += fmt.Sprintf("[%d]: %s\n", i+1, ref)
+		}
+		out = strings.TrimSuffix(out, "\n")
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package soup
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const markdownHTML = `<html><head><title>Skipped</title></head><body>
+<h1>Release  <em>notes</em></h1>
+<p>Read the <a href="/docs/intro" title="Intro">introduction</a> or <a href="https://example.org/">the site</a>,
+then run <code>go get</code>.<br>It uses *stars* and a <b>bold <i>move</i></b>.</p>
+<ul>
+	<li>One</li>
+	<li>Two
+		<ol start="3"><li>Three</li><li><a href="/docs/intro">Four</a></li></ol>
+	</li>
+</ul>
+<blockquote><p>Quoted</p><p>twice</p></blockquote>
+<pre class="language-go"><code>fmt.Println("hi")
+</code></pre>
+<table><thead><tr><th>Name</th><th>Notes</th></tr></thead>
+<tbody><tr><td>a|b</td><td><img src="img/x.png" alt="X"></td></tr><tr><td>c</td></tr></tbody></table>
+<hr>
+<script>ignored()</script>
+<p>Done <del>not</del></p>
+</body></html>`
+
+func TestMarkdown(t *testing.T) {
+	page := HTMLParse(markdownHTML)
+	assert.Equal(t, "# Release *notes*\n\n"+
+		"Read the [introduction](/docs/intro \"Intro\") or [the site](https://example.org/), then run `go get`.  \n"+
+		"It uses \\*stars\\* and a **bold *move***.\n\n"+
+		"- One\n- Two\n  3. Three\n  4. [Four](/docs/intro)\n\n"+
+		"> Quoted\n>\n> twice\n\n"+
+		"```go\nfmt.Println(\"hi\")\n```\n\n"+
+		"| Name | Notes |\n| --- | --- |\n| a\\|b | ![X](img/x.png) |\n| c |  |\n\n"+
+		"---\n\n"+
+		"Done ~~not~~", page.Markdown(MarkdownOptions{}))
+	assert.Equal(t, "[the site](https://example.org/)", page.Find("a", "href", "https://example.org/").Markdown(MarkdownOptions{}))
+	assert.Equal(t, "", Root{}.Markdown(MarkdownOptions{}))
+}
+
+func TestMarkdownEscapesLineStarts(t *testing.T) {
+	tests := map[string]string{
+		"<p># not a heading</p>":       `\# not a heading`,
+		"<p>1. not a list</p>":         `1\. not a list`,
+		"<p>2024) not a list</p>":      `2024\) not a list`,
+		"<p>- not a list</p>":          `\- not a list`,
+		"<p>+ not a list</p>":          `\+ not a list`,
+		"<p>&gt; not a quote</p>":      `\> not a quote`,
+		"<p>Title<br>=====</p>":        "Title  \n\\=====",
+		"<p>one<br># two</p>":          "one  \n\\# two",
+		"<ul><li>1. item</li></ul>":    `- 1\. item`,
+		"<p>a # b - c 1. d</p>":        "a # b - c 1. d",
+		"<p>1999 was a year</p>":       "1999 was a year",
+		"<h2># tag</h2>":               "## # tag",
+		"<blockquote>- x</blockquote>": `> \- x`,
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, HTMLParse(in).Markdown(MarkdownOptions{}), in)
+	}
+}
+
+func TestMarkdownNestedTable(t *testing.T) {
+	page := HTMLParse(`<table><tr><th>Outer</th><th>Cell</th></tr>` +
+		`<tr><td>x<table><tr><td>in</td><td>ner</td></tr><tr><td>more</td></tr></table></td><td>y</td></tr></table>`)
+	assert.Equal(t, "| Outer | Cell |\n| --- | --- |\n| x in ner more | y |", page.Markdown(MarkdownOptions{}))
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	page := HTMLParse(markdownHTML)
+	base, _ := url.Parse("https://example.com/blog/post")
+	md := page.Find("ul").Markdown(MarkdownOptions{LinkStyle: LinkReference, BaseURL: base})
+	assert.Equal(t, "- One\n- Two\n  3. Three\n  4. [Four][1]\n\n[1]: https://example.com/docs/intro", md)
+
+	md = page.Find("table").Markdown(MarkdownOptions{BaseURL: base})
+	assert.Contains(t, md, "![X](https://example.com/blog/img/x.png)")
+
+	// the <base> of the document is resolved against the page URL
+	page = HTMLParse(`<head><base href="/static/"></head><body><a href="a b.html">A</a> <a href="a b.html">again</a></body>`)
+	md = page.Find("body").Markdown(MarkdownOptions{LinkStyle: LinkReference, BaseURL: base})
+	assert.Equal(t, "[A][1] [again][1]\n\n[1]: https://example.com/static/a%20b.html", md)
+	assert.Equal(t, "[A](<a b.html>)", HTMLParse(`<a href="a b.html">A</a>`).Find("a").Markdown(MarkdownOptions{}))
+}
+
+func TestMarkdownCode(t *testing.T) {
+	page := HTMLParse("<p>Use <code>a ` b</code></p><pre>```\nfenced\n```</pre>")
+	assert.Equal(t, "Use ``a ` b``\n\n````\n```\nfenced\n```\n````", page.Markdown(MarkdownOptions{}))
+}
@@ -0,0 +1,317 @@
+package soup
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Matcher is a condition on an element, as taken by FindBy and FindAllBy.
+// A compiled *Selector is also a Matcher
+type Matcher interface {
+	Match(r Root) bool
+}
+
+// StringMatcher is a condition on a string such as a tag name or attribute
+// value. Besides the matchers in this package, a *regexp.Regexp can be used
+type StringMatcher interface {
+	MatchString(s string) bool
+}
+
+// Func is a Matcher calling a function to decide whether an element matches
+type Func func(r Root) bool
+
+// Match calls f(r)
+func (f Func) Match(r Root) bool {
+	return f(r)
+}
+
+func (f Func) String() string {
+	return "func"
+}
+
+// StringFunc is a StringMatcher calling a function to decide whether a string matches
+type StringFunc func(s string) bool
+
+// MatchString calls f(s)
+func (f StringFunc) MatchString(s string) bool {
+	return f(s)
+}
+
+func (f StringFunc) String() string {
+	return "func"
+}
+
+// Exact matches strings equal to the given one
+type Exact string
+
+// MatchString reports whether s equals e
+func (e Exact) MatchString(s string) bool {
+	return s == string(e)
+}
+
+func (e Exact) String() string {
+	return fmt.Sprintf("%q", string(e))
+}
+
+// stringMatcher is a StringMatcher with a description for error messages
+type stringMatcher struct {
+	desc  string
+	match func(s string) bool
+}
+
+func (m stringMatcher) MatchString(s string) bool {
+	return m.match(s)
+}
+
+func (m stringMatcher) String() string {
+	return m.desc
+}
+
+// Prefix matches strings starting with prefix
+func Prefix(prefix string) StringMatcher {
+	return stringMatcher{fmt.Sprintf("prefix %q", prefix), func(s string) bool {
+		return strings.HasPrefix(s, prefix)
+	}}
+}
+
+// Suffix matches strings ending with suffix
+func Suffix(suffix string) StringMatcher {
+	return stringMatcher{fmt.Sprintf("suffix %q", suffix), func(s string) bool {
+		return strings.HasSuffix(s, suffix)
+	}}
+}
+
+// Contains matches strings containing substr
+func Contains(substr string) StringMatcher {
+	return stringMatcher{fmt.Sprintf("containing %q", substr), func(s string) bool {
+		return strings.Contains(s, substr)
+	}}
+}
+
+// Word matches strings which, split on whitespace, contain word,
+// like a class name among the classes of an element
+func Word(word string) StringMatcher {
+	return stringMatcher{fmt.Sprintf("word %q", word), func(s string) bool {
+		for _, f := range strings.Fields(s) {
+			if f == word {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// OneOf matches strings equal to any of the given ones
+func OneOf(values ...string) StringMatcher {
+	return stringMatcher{fmt.Sprintf("one of %q", values), func(s string) bool {
+		for _, v := range values {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// Trimmed matches strings which, with leading and trailing whitespace
+// removed, match m. Text in HTML is usually surrounded by whitespace,
+// so this is useful with Exact when searching by text
+func Trimmed(m StringMatcher) StringMatcher {
+	return stringMatcher{fmt.Sprintf("trimmed %v", m), func(s string) bool {
+		return m.MatchString(strings.TrimSpace(s))
+	}}
+}
+
+// elementMatcher is a Matcher with a description for error messages
+type elementMatcher struct {
+	desc  string
+	match func(n *html.Node) bool
+}
+
+func (m elementMatcher) Match(r Root) bool {
+	return r.Pointer != nil && r.Pointer.Type == html.ElementNode && m.match(r.Pointer)
+}
+
+func (m elementMatcher) String() string {
+	return m.desc
+}
+
+// Tag matches elements whose tag name matches name. In XML documents,
+// the tag name includes the namespace prefix, as in "atom:link"
+func Tag(name StringMatcher) Matcher {
+	return elementMatcher{fmt.Sprintf("tag %v", name), func(n *html.Node) bool {
+		if n.Namespace != "" && inXMLDocument(n) {
+			return name.MatchString(n.Namespace + ":" + n.Data)
+		}
+		return name.MatchString(n.Data)
+	}}
+}
+
+// Attr matches elements having the attribute key with a value matching val
+func Attr(key string, val StringMatcher) Matcher {
+	return elementMatcher{fmt.Sprintf("attribute %s %v", key, val), func(n *html.Node) bool {
+		for _, attr := range n.Attr {
+			if attr.Key == key && val.MatchString(attr.Val) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// HasAttr matches elements having the attribute key, whatever its value
+func HasAttr(key string) Matcher {
+	return elementMatcher{fmt.Sprintf("attribute %s", key), func(n *html.Node) bool {
+		for _, attr := range n.Attr {
+			if attr.Key == key {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// AnyOf matches elements matching at least one of the given matchers
+func AnyOf(matchers ...Matcher) Matcher {
+	return elementMatcher{fmt.Sprintf("any of %v", matchers), func(n *html.Node) bool {
+		r := Root{Pointer: n, NodeValue: n.Data}
+		for _, m := range matchers {
+			if m.Match(r) {
+				return true
+			}
+		}
+		return false
+	}}
+}
+
+// matchesAll returns a function reporting whether a node matches all of the matchers
+func matchesAll(matchers []Matcher) func(*html.Node) bool {
+	return func(n *html.Node) bool {
+		r := Root{Pointer: n, NodeValue: n.Data}
+		for _, m := range matchers {
+			if !m.Match(r) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// describeMatchers formats matchers for error messages
+func describeMatchers(matchers []Matcher) string {
+	desc := make([]string, 0, len(matchers))
+	for _, m := range matchers {
+		desc = append(desc, fmt.Sprint(m))
+	}
+	return strings.Join(desc, ", ")
+}
+
+// FindBy finds the first element below r matching all of the given matchers
+func (r Root) FindBy(matchers ...Matcher) Root {
+	temp, ok := matchOnce(r.Pointer, matchesAll(matchers))
+	if !ok {
+		if debug {
+			panic("Element matching `" + describeMatchers(matchers) + "` not found")
+		}
+		return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("element matching `%s` not found%s", describeMatchers(matchers), searchedFrom(r.Pointer)))}
+	}
+	return Root{Pointer: temp, NodeValue: temp.Data}
+}
+
+// FindAllBy finds all elements below r matching all of the given matchers
+func (r Root) FindAllBy(matchers ...Matcher) []Root {
+	return r.FindAllByWith(FindOptions{}, matchers...)
+}
+
+// FindAllByWith is like FindAllBy, but stops after opts.Limit matches
+// and only looks at direct children when opts.NonRecursive is set
+func (r Root) FindAllByWith(opts FindOptions, matchers ...Matcher) []Root {
+	temp := matchAll(r.Pointer, matchesAll(matchers), opts)
+	if len(temp) == 0 {
+		if debug {
+			panic("Element matching `" + describeMatchers(matchers) + "` not found")
+		}
+		return []Root{}
+	}
+	pointers := make([]Root, 0, len(temp))
+	for i := 0; i < len(temp); i++ {
+		pointers = append(pointers, Root{Pointer: temp[i], NodeValue: temp[i].Data})
+	}
+	return pointers
+}
+
+// Text matches elements whose own text, the text directly inside
+// them but not inside their child elements, matches m
+func Text(m StringMatcher) Matcher {
+	return elementMatcher{fmt.Sprintf("text %v", m), func(n *html.Node) bool {
+		return m.MatchString(ownText(n))
+	}}
+}
+
+// FullText matches elements whose full text, including the text
+// inside their child elements, matches m
+func FullText(m StringMatcher) Matcher {
+	return elementMatcher{fmt.Sprintf("full text %v", m), func(n *html.Node) bool {
+		return m.MatchString(Root{Pointer: n}.FullText())
+	}}
+}
+
+// ownText joins the text nodes that are direct children of n
+func ownText(n *html.Node) string {
+	var buf strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			buf.WriteString(c.Data)
+		}
+	}
+	return buf.String()
+}
+
+// FindString finds the first text node below r matching m, so that
+// searches can start from a piece of text like "Next page". The parent
+// of the text node is the element containing it
+func (r Root) FindString(m StringMatcher) Root {
+	var temp *html.Node
+	if r.Pointer != nil {
+		walkDescendants(r.Pointer, func(c *html.Node) bool {
+			if c.Type == html.TextNode && m.MatchString(c.Data) {
+				temp = c
+				return true
+			}
+			return false
+		})
+	}
+	if temp == nil {
+		if debug {
+			panic(fmt.Sprintf("Text matching `%v` not found", m))
+		}
+		return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("text matching `%v` not found%s", m, searchedFrom(r.Pointer)))}
+	}
+	return Root{Pointer: temp, NodeValue: temp.Data}
+}
+
+// FindAllStrings finds all text nodes below r matching m
+func (r Root) FindAllStrings(m StringMatcher) []Root {
+	var temp []*html.Node
+	if r.Pointer != nil {
+		walkDescendants(r.Pointer, func(c *html.Node) bool {
+			if c.Type == html.TextNode && m.MatchString(c.Data) {
+				temp = append(temp, c)
+			}
+			return false
+		})
+	}
+	if len(temp) == 0 {
+		if debug {
+			panic(fmt.Sprintf("Text matching `%v` not found", m))
+		}
+		return []Root{}
+	}
+	pointers := make([]Root, 0, len(temp))
+	for i := 0; i < len(temp); i++ {
+		pointers = append(pointers, Root{Pointer: temp[i], NodeValue: temp[i].Data})
+	}
+	return pointers
+}
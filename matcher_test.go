@@ -0,0 +1,84 @@
+package soup
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindByTagAndAttr(t *testing.T) {
+	links := selectDoc.FindAllBy(Tag(Exact("a")), Attr("href", Prefix("https://")))
+	assert.Equal(t, []string{"secure"}, selectedText(links))
+
+	headings := selectDoc.FindAllBy(Tag(regexp.MustCompile(`^h[1-6]$`)))
+	assert.Equal(t, []string{"Heading"}, selectedText(headings))
+
+	items := selectDoc.FindAllBy(Tag(OneOf("h2", "li")), HasAttr("class"))
+	assert.Equal(t, []string{"Two"}, selectedText(items))
+
+	assert.Equal(t, "ul", selectDoc.FindBy(Attr("data-kind", Word("menu"))).NodeValue)
+	pdfOrTitled := selectDoc.FindAllBy(Tag(Exact("a")), AnyOf(Attr("href", Suffix(".pdf")), HasAttr("title")))
+	assert.Equal(t, []string{"secure"}, selectedText(pdfOrTitled))
+	assert.Equal(t, 2, len(selectDoc.FindAllBy(Attr("href", Contains("example")))))
+}
+
+func TestFindByFuncAndSelector(t *testing.T) {
+	long := selectDoc.FindAllBy(Tag(Exact("li")), Func(func(r Root) bool {
+		return len(r.Text()) > 3
+	}))
+	assert.Equal(t, []string{"Three"}, selectedText(long))
+
+	upper := selectDoc.FindBy(MustCompile("#main p"), Attr("class", StringFunc(func(s string) bool {
+		return strings.ToUpper(s) == "INTRO"
+	})))
+	assert.Equal(t, "First", upper.Text())
+}
+
+func TestFindByNotFound(t *testing.T) {
+	r := selectDoc.FindBy(Tag(Exact("a")), Attr("href", Prefix("ftp://")))
+	assert.Equal(t, ErrElementNotFound, r.Error.(Error).Type)
+	assert.Equal(t, "element matching `tag \"a\", attribute href prefix \"ftp://\"` not found", r.Error.Error())
+	assert.Empty(t, selectDoc.FindAllBy(HasAttr("title")))
+	assert.Empty(t, selectDoc.Find("missing").FindAllBy(Tag(Exact("a"))))
+}
+
+const pagerHTML = `
+<div class="pager">
+  <span>Page 2 of 9</span>
+  <a href="/p/1">Previous page</a>
+  <a href="/p/3"> Next page </a>
+  <p>Price: <b>$10</b></p>
+</div>
+`
+
+func TestFindString(t *testing.T) {
+	pager := HTMLParse(pagerHTML)
+
+	next := pager.FindString(Trimmed(Exact("Next page")))
+	assert.NoError(t, next.Error)
+	assert.Equal(t, " Next page ", next.NodeValue)
+	assert.Equal(t, "/p/3", Root{Pointer: next.Pointer.Parent}.Attrs()["href"])
+
+	price := pager.FindString(Contains("Price:"))
+	assert.Equal(t, "b", price.FindNextElementSibling().NodeValue)
+
+	pages := pager.FindAllStrings(regexp.MustCompile(`(?i)page`))
+	assert.Equal(t, 3, len(pages))
+
+	missing := pager.FindString(Exact("Last page"))
+	assert.Equal(t, ErrElementNotFound, missing.Error.(Error).Type)
+	assert.Empty(t, pager.FindAllStrings(Exact("Last page")))
+}
+
+func TestFindByText(t *testing.T) {
+	pager := HTMLParse(pagerHTML)
+	assert.Equal(t, "/p/1", pager.FindBy(Tag(Exact("a")), Text(Prefix("Prev"))).Attrs()["href"])
+	assert.Equal(t, "p", pager.FindBy(Text(Trimmed(Exact("Price:")))).NodeValue)
+	assert.Equal(t, "p", pager.FindBy(Tag(Exact("p")), FullText(Contains("$10"))).NodeValue)
+	assert.Empty(t, pager.FindAllBy(Text(Contains("$10")), Tag(Exact("p"))))
+	assert.Equal(t, 2, len(pager.FindAllBy(FullText(StringFunc(func(s string) bool {
+		return strings.Contains(s, "page") && !strings.Contains(s, "Price")
+	})), Tag(Exact("a")))))
+}
@@ -0,0 +1,145 @@
+package soup
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/transform"
+)
+
+// ParseOptions controls how HTMLParseReader reads a document
+type ParseOptions struct {
+	// ContentType is the Content-Type header the document was served
+	// with. Its charset parameter is used unless the document starts
+	// with a byte order mark or the text doesn't fit it
+	ContentType string
+	// Encoding, when set, is the encoding the document is decoded from,
+	// such as "euc-kr", whatever the document or ContentType say. An
+	// unknown encoding is reported as ErrUnknownEncoding
+	Encoding string
+	// TrackPositions records where in the source every element, string
+	// and comment comes from, for Position to return and for the errors
+	// of Find and the like to point at. The whole document is read into
+	// memory to do so
+	TrackPositions bool
+	// ParseOnly, when set, keeps only the elements matching it, along
+	// with everything inside them, like the SoupStrainer of BeautifulSoup.
+	// They are put in the <body> of an otherwise empty document, and the
+	// rest of the document is never built, so that huge documents can be
+	// searched with little memory. ParseOnly only sees the name and
+	// attributes of elements, not their parents or contents. Positions
+	// aren't tracked for documents parsed this way
+	ParseOnly Matcher
+}
+
+// encodingAttr is the attribute of the document node holding the
+// encoding the document was decoded from
+const encodingAttr = "encoding"
+
+// HTMLParseReader parses the HTML read from r, returning a start pointer
+// to the DOM. The document is decoded to UTF-8 as it is read, from
+// opts.Encoding if it is set, and otherwise from the encoding
+// DetectEncoding finds in the beginning of the document. The encoding
+// used is returned by Encoding
+func HTMLParseReader(r io.Reader, opts ParseOptions) Root {
+	// one byte more than DetectEncoding looks at, so that it knows when it only sees part of the document
+	br := bufio.NewReaderSize(r, detectLen+1)
+	peek, err := br.Peek(detectLen + 1)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		if debug {
+			panic("Unable to read the HTML")
+		}
+		return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to read the HTML: %v", err))}
+	}
+	label := opts.Encoding
+	if label == "" {
+		label = DetectEncoding(peek, opts.ContentType).Encoding
+	}
+	enc, name, err := lookupEncoding(label)
+	if err != nil {
+		return Root{Error: err}
+	}
+	if bom := byteOrderMark(peek); bom > 0 {
+		br.Discard(bom)
+	}
+	var input io.Reader = br
+	if name != "utf-8" {
+		input = transform.NewReader(br, enc.NewDecoder())
+	}
+	if opts.ParseOnly != nil {
+		doc, err := parseOnly(input, opts.ParseOnly)
+		if err != nil {
+			if debug {
+				panic("Unable to parse the HTML")
+			}
+			return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to parse the HTML: %v", err))}
+		}
+		doc.Attr = append(doc.Attr, html.Attribute{Key: encodingAttr, Val: name})
+		return documentElement(doc)
+	}
+	var src []byte
+	if opts.TrackPositions {
+		if src, err = io.ReadAll(input); err != nil {
+			if debug {
+				panic("Unable to read the HTML")
+			}
+			return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to read the HTML: %v", err))}
+		}
+		input = bytes.NewReader(src)
+	}
+	doc, err := html.Parse(input)
+	if err != nil {
+		if debug {
+			panic("Unable to parse the HTML")
+		}
+		return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to parse the HTML: %v", err))}
+	}
+	if opts.TrackPositions {
+		trackPositions(doc, src)
+	}
+	doc.Attr = append(doc.Attr, html.Attribute{Key: encodingAttr, Val: name})
+	return documentElement(doc)
+}
+
+// HTMLParseBytes is like HTMLParseReader, for a document which has
+// already been read, such as the body of a response served with contentType
+func HTMLParseBytes(b []byte, contentType string) Root {
+	return HTMLParseReader(bytes.NewReader(b), ParseOptions{ContentType: contentType})
+}
+
+// byteOrderMark returns the length of the byte order mark b starts with, if any
+func byteOrderMark(b []byte) int {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		return 3
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}), bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		return 2
+	}
+	return 0
+}
+
+// Encoding returns the name of the character encoding the document was
+// decoded from, such as "utf-8" or "shift_jis", when it was parsed by
+// HTMLParseReader or HTMLParseBytes. It is empty for HTMLParse, which
+// takes a string that is already UTF-8
+func (r Root) Encoding() string {
+	if r.Pointer == nil {
+		return ""
+	}
+	doc := r.Pointer
+	for doc.Parent != nil {
+		doc = doc.Parent
+	}
+	if doc.Type != html.DocumentNode {
+		return ""
+	}
+	for _, attr := range doc.Attr {
+		if attr.Key == encodingAttr {
+			return attr.Val
+		}
+	}
+	return ""
+}
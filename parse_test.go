@@ -0,0 +1,62 @@
+package soup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestHTMLParseReader(t *testing.T) {
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String(`<html><head><meta charset="Shift_JIS"><title>日本語</title></head><body><p>こんにちは</p></body></html>`)
+	page := HTMLParseReader(strings.NewReader(sjis), ParseOptions{})
+	assert.NoError(t, page.Error)
+	assert.Equal(t, "shift_jis", page.Encoding())
+	assert.Equal(t, "日本語", page.Find("title").Text())
+	assert.Equal(t, "shift_jis", page.Find("p").Encoding())
+
+	// http-equiv declarations are found as well
+	sjis, _ = japanese.ShiftJIS.NewEncoder().String(`<meta http-equiv="Content-Type" content="text/html; charset=shift_jis"><p>こんにちは</p>`)
+	page = HTMLParseReader(strings.NewReader(sjis), ParseOptions{})
+	assert.Equal(t, "こんにちは", page.Find("p").Text())
+
+	// without any declaration, non UTF-8 text is taken to be windows-1252
+	page = HTMLParseReader(bytes.NewReader([]byte("<p>caf\xe9</p>")), ParseOptions{})
+	assert.Equal(t, "windows-1252", page.Encoding())
+	assert.Equal(t, "café", page.Find("p").Text())
+	page = HTMLParseReader(strings.NewReader("<p>café</p>"), ParseOptions{})
+	assert.Equal(t, "utf-8", page.Encoding())
+	assert.Equal(t, "café", page.Find("p").Text())
+
+	assert.Equal(t, "", HTMLParse("<p>café</p>").Encoding())
+	assert.Equal(t, "", Root{}.Encoding())
+}
+
+func TestHTMLParseBytes(t *testing.T) {
+	// the header wins over the document
+	page := HTMLParseBytes([]byte(`<meta charset="utf-8"><p>caf`+"\xe9"+`</p>`), "text/html; charset=ISO-8859-1")
+	assert.Equal(t, "windows-1252", page.Encoding())
+	assert.Equal(t, "café", page.Find("p").Text())
+
+	// and a byte order mark wins over both
+	utf16, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(`<p>café</p>`))
+	page = HTMLParseBytes(utf16, "text/html; charset=ISO-8859-1")
+	assert.Equal(t, "utf-16le", page.Encoding())
+	assert.Equal(t, "café", page.Find("p").Text())
+	assert.Equal(t, "<p>café</p>", page.Find("p").HTML())
+
+	page = HTMLParseBytes([]byte("\xef\xbb\xbf<p>café</p>"), "")
+	assert.Equal(t, "utf-8", page.Encoding())
+	assert.Equal(t, "<html><head></head><body><p>café</p></body></html>", page.HTML())
+}
+
+func TestXMLParseReaderEncoding(t *testing.T) {
+	utf16, _ := unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(`<?xml version="1.0" encoding="UTF-16"?><a>é</a>`))
+	root := XMLParseReader(bytes.NewReader(utf16))
+	assert.NoError(t, root.Error)
+	assert.Equal(t, "utf-16be", root.Encoding())
+	assert.Equal(t, "é", root.Text())
+}
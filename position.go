@@ -0,0 +1,281 @@
+package soup
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"weak"
+
+	"golang.org/x/net/html"
+)
+
+// Location is a place in the source of a document
+type Location struct {
+	// Offset is the number of bytes of the source before the location
+	Offset int
+	// Line and Column count from 1, with Column counted in characters
+	Line, Column int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("line %d, column %d", l.Line, l.Column)
+}
+
+// Position is the part of the source of a document a node was parsed
+// from. For an element, it goes from the start of its start tag to the
+// end of its end tag, or of its last child when it has no end tag
+type Position struct {
+	Start, End Location
+}
+
+// nodePositions holds the positions of the nodes of a document. Nodes are
+// held by weak pointers, so that the document can still be garbage collected
+type nodePositions map[weak.Pointer[html.Node]]Position
+
+// positions holds the nodePositions of every document parsed with
+// TrackPositions, by a weak pointer to its document node
+var positions sync.Map
+
+// resyncWindow is how far trackPositions looks ahead for a match when the
+// tree and the source no longer line up
+const resyncWindow = 64
+
+// srcToken is a token of the source of a document which turns into a node
+type srcToken struct {
+	typ  html.TokenType
+	name string
+	text string
+	// start and end are the offsets of the token, and close the end of
+	// the end tag matching a start tag, or -1 when there isn't one
+	start, end, close int
+}
+
+// trackPositions records the positions of the nodes of doc, which was
+// parsed from src. The tree builder adds, drops, merges and moves nodes,
+// so the nodes are lined up with the tokens of src, skipping whatever
+// doesn't line up. Nodes which don't come from the source, such as an
+// implied <tbody>, get no position
+func trackPositions(doc *html.Node, src []byte) {
+	toks := tokenize(src)
+	var nodes []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			switch c.Type {
+			case html.ElementNode, html.TextNode, html.CommentNode:
+				nodes = append(nodes, c)
+			}
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	lines := []int{0}
+	for i, c := range src {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	location := func(offset int) Location {
+		line := sort.SearchInts(lines, offset+1) - 1
+		return Location{Offset: offset, Line: line + 1, Column: utf8.RuneCount(src[lines[line]:offset]) + 1}
+	}
+
+	found := make(map[*html.Node]srcToken)
+	// nodes the tree builder has moved are lined up with what is left over
+	nodes, toks = align(nodes, toks, found)
+	align(nodes, toks, found)
+
+	pos := make(nodePositions, len(found))
+	var end func(*html.Node) int
+	end = func(n *html.Node) int {
+		last := -1
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			last = max(last, end(c))
+		}
+		tok, ok := found[n]
+		if !ok {
+			return last
+		}
+		e := tok.end
+		if tok.close >= 0 {
+			e = tok.close
+		} else if n.Type == html.ElementNode {
+			e = max(e, last)
+		}
+		pos[weak.Make(n)] = Position{Start: location(tok.start), End: location(e)}
+		return e
+	}
+	end(doc)
+
+	key := weak.Make(doc)
+	positions.Store(key, pos)
+	runtime.AddCleanup(doc, func(key weak.Pointer[html.Node]) {
+		positions.Delete(key)
+	}, key)
+}
+
+// align lines up nodes with toks, adding the token each node comes from to
+// found, and returns the nodes and tokens which were left over
+func align(nodes []*html.Node, toks []srcToken, found map[*html.Node]srcToken) ([]*html.Node, []srcToken) {
+	var restNodes []*html.Node
+	var restToks []srcToken
+	i, j := 0, 0
+	for i < len(nodes) && j < len(toks) {
+		if n := fits(nodes[i], toks[j]); n >= 0 {
+			tok := toks[j]
+			// text the tree builder has merged together
+			for nodes[i].Type == html.TextNode && n < len(nodes[i].Data) && j+1 < len(toks) && toks[j+1].typ == html.TextToken &&
+				toks[j+1].text != "" && strings.HasPrefix(nodes[i].Data[n:], toks[j+1].text) {
+				j++
+				n += len(toks[j].text)
+				tok.end = toks[j].end
+			}
+			found[nodes[i]] = tok
+			i, j = i+1, j+1
+			continue
+		}
+		// resynchronise on whichever side has less to skip
+		skipNodes, skipToks := -1, -1
+		for k := 1; k < resyncWindow && (skipNodes < 0 || skipToks < 0); k++ {
+			if skipNodes < 0 && i+k < len(nodes) && fits(nodes[i+k], toks[j]) >= 0 {
+				skipNodes = k
+			}
+			if skipToks < 0 && j+k < len(toks) && fits(nodes[i], toks[j+k]) >= 0 {
+				skipToks = k
+			}
+		}
+		switch {
+		case skipToks > 0 && (skipNodes < 0 || skipToks <= skipNodes):
+			restToks = append(restToks, toks[j:j+skipToks]...)
+			j += skipToks
+		case skipNodes > 0:
+			restNodes = append(restNodes, nodes[i:i+skipNodes]...)
+			i += skipNodes
+		default:
+			restNodes, restToks = append(restNodes, nodes[i]), append(restToks, toks[j])
+			i, j = i+1, j+1
+		}
+	}
+	return append(restNodes, nodes[i:]...), append(restToks, toks[j:]...)
+}
+
+// tokenize splits src into the tokens which turn into nodes, matching
+// end tags to the start tags they close. Elements whose end tag is left
+// out are closed by the start tags which imply it, and an end tag only
+// closes the element it names, since the tree builder keeps misnested
+// elements such as the <p> in <b><p></b></p> open
+func tokenize(src []byte) []srcToken {
+	var toks []srcToken
+	var open []int
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for offset := 0; ; {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return toks
+		}
+		start := offset
+		offset += len(z.Raw())
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			if k := impliedEnd(toks, open, string(name)); k >= 0 {
+				open = append(open[:k], open[k+1:]...)
+			}
+			toks = append(toks, srcToken{typ: html.StartTagToken, name: string(name), start: start, end: offset, close: -1})
+			if tt == html.StartTagToken && !voidElements[string(name)] {
+				open = append(open, len(toks)-1)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for k := len(open) - 1; k >= 0; k-- {
+				if toks[open[k]].name == string(name) {
+					toks[open[k]].close = offset
+					open = append(open[:k], open[k+1:]...)
+					break
+				}
+			}
+		case html.TextToken, html.CommentToken:
+			toks = append(toks, srcToken{typ: tt, text: string(z.Text()), start: start, end: offset, close: -1})
+		}
+	}
+}
+
+// impliedEnd returns the index in open of the element whose end tag the
+// start tag name implies, like the <li> before another <li>, or -1
+func impliedEnd(toks []srcToken, open []int, name string) int {
+	for k := len(open) - 1; k >= 0; k-- {
+		e := toks[open[k]].name
+		if e == "p" && blockElements[name] || closedBy[e][name] {
+			return k
+		}
+		if blockElements[e] && e != "div" && e != "p" {
+			// a block in between, like the <ul> of a nested list, keeps it open
+			return -1
+		}
+	}
+	return -1
+}
+
+// fits reports whether the node n can come from tok, returning how much
+// of the text of a text node tok accounts for, or -1 if it doesn't fit
+func fits(n *html.Node, tok srcToken) int {
+	switch {
+	case n.Type == html.ElementNode && tok.typ == html.StartTagToken && strings.EqualFold(n.Data, tok.name):
+		return 0
+	case n.Type == html.CommentNode && tok.typ == html.CommentToken && n.Data == tok.text:
+		return 0
+	case n.Type == html.TextNode && tok.typ == html.TextToken:
+		text := tok.text
+		if !strings.HasPrefix(n.Data, "\n") {
+			// the newline right after <pre> is dropped
+			text = strings.TrimPrefix(text, "\n")
+		}
+		switch {
+		case text == "":
+			return -1
+		case strings.HasPrefix(n.Data, text):
+			return len(text)
+		case strings.HasPrefix(text, n.Data):
+			return len(n.Data)
+		}
+	}
+	return -1
+}
+
+// Position returns the part of the source the node was parsed from, for
+// documents parsed by HTMLParseReader with TrackPositions. Offsets count
+// bytes of the document once decoded to UTF-8. It reports false when
+// positions weren't tracked, or the node doesn't come from the source
+func (r Root) Position() (Position, bool) {
+	if r.Pointer == nil {
+		return Position{}, false
+	}
+	doc := r.Pointer
+	for doc.Parent != nil {
+		doc = doc.Parent
+	}
+	pos, ok := positions.Load(weak.Make(doc))
+	if !ok {
+		return Position{}, false
+	}
+	p, ok := pos.(nodePositions)[weak.Make(r.Pointer)]
+	return p, ok
+}
+
+// searchedFrom describes where a search starting at n took place, for
+// error messages, when the position of n is known
+func searchedFrom(n *html.Node) string {
+	if n == nil || n.Type != html.ElementNode {
+		return ""
+	}
+	pos, ok := Root{Pointer: n}.Position()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" in <%s> at %s", n.Data, pos.Start)
+}
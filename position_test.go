@@ -0,0 +1,150 @@
+package soup
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"weak"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const positionPage = `<!DOCTYPE html>
+<html>
+<head><title>Prices</title></head>
+<body>
+  <div id="main">
+    <p>Caf&eacute; <b>au lait</b></p>
+    <table>
+      <tr><td>€3</td></tr>
+    </table>
+    <!-- prices -->
+    <ul><li>one<li>two</ul>
+  </div>
+</body>
+</html>`
+
+func trackedPage(s string) Root {
+	return HTMLParseReader(strings.NewReader(s), ParseOptions{TrackPositions: true})
+}
+
+// source returns the part of the source the node was parsed from
+func source(t *testing.T, r Root, src string) string {
+	pos, ok := r.Position()
+	if !assert.True(t, ok, r.NodeValue) {
+		return ""
+	}
+	return src[pos.Start.Offset:pos.End.Offset]
+}
+
+func TestPosition(t *testing.T) {
+	page := trackedPage(positionPage)
+	assert.NoError(t, page.Error)
+
+	div := page.Find("div", "id", "main")
+	pos, ok := div.Position()
+	assert.True(t, ok)
+	assert.Equal(t, Location{Offset: 67, Line: 5, Column: 3}, pos.Start)
+	assert.Equal(t, Location{Offset: 231, Line: 12, Column: 9}, pos.End)
+	assert.Equal(t, "line 5, column 3", pos.Start.String())
+
+	assert.Equal(t, "<b>au lait</b>", source(t, page.Find("b"), positionPage))
+	assert.Equal(t, "Caf&eacute; ", source(t, page.Find("p").Children()[0], positionPage))
+	assert.Equal(t, "<title>Prices</title>", source(t, page.Find("title"), positionPage))
+	assert.Equal(t, "<!-- prices -->", source(t, div.Children()[5], positionPage))
+
+	// columns count characters, not bytes
+	pos, _ = page.Find("td").Children()[0].Position()
+	assert.Equal(t, Location{Offset: 147, Line: 8, Column: 15}, pos.Start)
+	assert.Equal(t, Location{Offset: 151, Line: 8, Column: 17}, pos.End)
+
+	// elements without an end tag end with their last child
+	assert.Equal(t, "<li>one", source(t, page.Find("li"), positionPage))
+
+	// the <tbody> is added by the parser
+	_, ok = page.Find("tbody").Position()
+	assert.False(t, ok)
+	assert.Equal(t, "<tr><td>€3</td></tr>", source(t, page.Find("tr"), positionPage))
+}
+
+func TestPositionRepairedHTML(t *testing.T) {
+	src := "<table><tr><td>1</td></tr><div>moved</div><tr><td>2</td></tr></table><p><b>bold<i>both</b>italic</i></p>\n<pre>\ntext</pre>"
+	page := trackedPage(src)
+	// the <div> is moved out of the table, in front of it
+	assert.Equal(t, "<div>moved</div>", source(t, page.Find("div"), src))
+	assert.Equal(t, "moved", source(t, page.Find("div").Children()[0], src))
+	tds := page.FindAll("td")
+	assert.Equal(t, "<td>1</td>", source(t, tds[0], src))
+	assert.Equal(t, "<td>2</td>", source(t, tds[1], src))
+	assert.Equal(t, "<i>", source(t, page.Find("i"), src)[:3])
+	assert.Equal(t, "\ntext", source(t, page.Find("pre").Children()[0], src))
+}
+
+func TestPositionMisnestedAndFostered(t *testing.T) {
+	// a misnested end tag doesn't close the elements it is out of order with
+	src := "<b>1<p>2</b>3</p>"
+	page := trackedPage(src)
+	assert.Equal(t, "<b>1<p>2</b>", source(t, page.Find("b"), src))
+	assert.Equal(t, "<p>2</b>3</p>", source(t, page.Find("p"), src))
+	assert.Equal(t, "3", source(t, page.Find("p").Children()[1], src))
+
+	// an element moved out of the table doesn't shift what comes after it
+	src = "<table><li>3</li><tr><td>t</td></tr></table>"
+	page = trackedPage(src)
+	assert.Equal(t, "<li>3</li>", source(t, page.Find("li"), src))
+	assert.Equal(t, "<td>t</td>", source(t, page.Find("td"), src))
+	assert.Equal(t, "t", source(t, page.Find("td").Children()[0], src))
+
+	// end tags which are left out are implied by the next start tag
+	src = "<ul><li>one<li>two</li></ul><p>a<div>b</div></p>"
+	page = trackedPage(src)
+	assert.Equal(t, "<li>one", source(t, page.Find("li"), src))
+	assert.Equal(t, "<li>two</li>", source(t, page.FindAll("li")[1], src))
+	assert.Equal(t, "<p>a", source(t, page.Find("p"), src))
+}
+
+func TestPositionNotTracked(t *testing.T) {
+	_, ok := HTMLParse(positionPage).Find("div").Position()
+	assert.False(t, ok)
+	_, ok = HTMLParseReader(strings.NewReader(positionPage), ParseOptions{}).Find("div").Position()
+	assert.False(t, ok)
+	_, ok = Root{}.Position()
+	assert.False(t, ok)
+
+	// nodes added later have no position
+	page := trackedPage(positionPage)
+	p := NewTag("p", nil)
+	assert.NoError(t, page.Find("div").Append(p))
+	_, ok = p.Position()
+	assert.False(t, ok)
+}
+
+func TestPositionInErrors(t *testing.T) {
+	page := trackedPage(positionPage)
+	div := page.Find("div", "id", "main")
+	err := div.Find("span").Error
+	assert.Equal(t, ErrElementNotFound, err.(Error).Type)
+	assert.Equal(t, "element `span` with attributes `` not found in <div> at line 5, column 3", err.Error())
+	assert.Contains(t, div.FindBy(Tag(Exact("span"))).Error.Error(), "in <div> at line 5, column 3")
+	assert.Contains(t, div.SelectOne("span").Error.Error(), "in <div> at line 5, column 3")
+	assert.Contains(t, div.FindString(Exact("missing")).Error.Error(), "in <div> at line 5, column 3")
+
+	// without positions, the errors are as they were
+	err = HTMLParse(positionPage).Find("span").Error
+	assert.Equal(t, "element `span` with attributes `` not found", err.Error())
+}
+
+func TestPositionsCollected(t *testing.T) {
+	doc := trackedPage(positionPage).Pointer.Parent
+	key := weak.Make(doc)
+	_, ok := positions.Load(key)
+	assert.True(t, ok)
+	doc = nil
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		if _, ok = positions.Load(key); !ok {
+			break
+		}
+	}
+	assert.False(t, ok)
+}
@@ -0,0 +1,390 @@
+package soup
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// EscapeMode decides which characters are written as entities
+type EscapeMode int
+
+const (
+	// EscapeMinimal only escapes the characters which have a meaning in
+	// HTML: &, <, >, " and ', like HTML does
+	EscapeMinimal EscapeMode = iota
+	// EscapeFull also writes every non-ASCII character as a numeric
+	// character reference, so that the output is plain ASCII
+	EscapeFull
+)
+
+// SelfClosingStyle decides how void elements such as <br> are written
+type SelfClosingStyle int
+
+const (
+	// SelfClosingSlash writes void elements as <br/>, like HTML does
+	SelfClosingSlash SelfClosingStyle = iota
+	// SelfClosingNone writes void elements as <br>, as HTML5 has them
+	SelfClosingNone
+	// SelfClosingSpace writes void elements as <br />, as XHTML has them
+	SelfClosingSpace
+)
+
+// FormatOptions controls how Format writes out HTML. The zero
+// FormatOptions writes the same HTML as the HTML function
+type FormatOptions struct {
+	// Pretty puts every tag and string on a line of its own, indented by
+	// Indent once for every level of nesting. Strings are trimmed, and
+	// the contents of <pre>, <textarea>, <script> and <style> are left as they are
+	Pretty bool
+	Indent string
+	// Escape decides which characters are written as entities
+	Escape EscapeMode
+	// SortAttrs writes attributes in alphabetical order instead of the order they appear in
+	SortAttrs bool
+	// SelfClosing decides how void elements are written
+	SelfClosing SelfClosingStyle
+	// XML writes XML instead of HTML: every element without children is
+	// self-closing, CDATA sections are kept and text is always escaped.
+	// Elements parsed by XMLParse are always written as XML, with their
+	// namespace prefix
+	XML bool
+}
+
+// literalElements hold text which is written without escaping
+var literalElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+	"plaintext": true, "script": true, "style": true, "xmp": true,
+}
+
+// voidElements cannot have any content, so they have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "keygen": true, "link": true,
+	"meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// renderer writes out nodes as HTML, keeping the first error it runs into
+type renderer struct {
+	w    io.Writer
+	opts FormatOptions
+	// prefixes tells whether to write the namespace prefixes of elements
+	prefixes bool
+	// done is set once a <plaintext> element has been written, since
+	// everything after it would be read back as its text
+	done bool
+	n    int64
+	err  error
+}
+
+func (r *renderer) write(s string) {
+	if r.err != nil || r.done {
+		return
+	}
+	n, err := io.WriteString(r.w, s)
+	r.n += int64(n)
+	r.err = err
+}
+
+func (r *renderer) escape(s string) {
+	var buf strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '&':
+			buf.WriteString("&amp;")
+		case c == '\'':
+			buf.WriteString("&#39;")
+		case c == '<':
+			buf.WriteString("&lt;")
+		case c == '>':
+			buf.WriteString("&gt;")
+		case c == '"':
+			buf.WriteString("&#34;")
+		case c == '\r':
+			buf.WriteString("&#13;")
+		case c >= utf8.RuneSelf && r.opts.Escape == EscapeFull:
+			if ch, size := utf8.DecodeRuneInString(s[i:]); ch != utf8.RuneError || size > 1 {
+				fmt.Fprintf(&buf, "&#%d;", ch)
+				i += size
+				continue
+			}
+			buf.WriteByte(c)
+		default:
+			buf.WriteByte(c)
+		}
+		i++
+	}
+	r.write(buf.String())
+}
+
+// node writes n and everything below it on a single line
+func (r *renderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if r.opts.XML && n.Namespace == cdataNamespace {
+			r.write("<![CDATA[" + strings.ReplaceAll(n.Data, "]]>", "]]]]><![CDATA[>") + "]]>")
+		} else {
+			r.escape(n.Data)
+		}
+	case html.DocumentNode:
+		r.children(n)
+	case html.ElementNode:
+		r.startTag(n)
+		if r.selfClosing(n) {
+			return
+		}
+		if c := n.FirstChild; c != nil && c.Type == html.TextNode && strings.HasPrefix(c.Data, "\n") {
+			switch n.Data {
+			case "pre", "listing", "textarea":
+				if !r.opts.XML {
+					r.write("\n")
+				}
+			}
+		}
+		r.children(n)
+		if n.Data == "plaintext" && r.literal(n) {
+			r.done = true
+			return
+		}
+		r.write("</" + r.tagName(n) + ">")
+	case html.CommentNode, html.DoctypeNode, html.RawNode:
+		// nothing about these depends on the options
+		if r.err == nil && !r.done {
+			r.err = html.Render(writeCounter{r}, n)
+		}
+	default:
+		r.fail(fmt.Sprintf("cannot render a node of type %d", n.Type))
+	}
+}
+
+// literal reports whether the text inside the element n is written
+// without escaping. As with html.Render, this isn't the case for an
+// HTML element inside SVG or MathML, outside of the elements which
+// hold HTML such as <foreignObject>
+func (r *renderer) literal(n *html.Node) bool {
+	if n.Type != html.ElementNode || r.opts.XML || n.Namespace != "" || !literalElements[n.Data] {
+		return false
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Namespace != "" {
+			return htmlIntegrationPoint(p)
+		}
+	}
+	return true
+}
+
+// htmlIntegrationPoint reports whether the SVG or MathML element n holds HTML
+func htmlIntegrationPoint(n *html.Node) bool {
+	switch n.Namespace + " " + n.Data {
+	case "svg foreignObject", "svg desc", "svg title":
+		return true
+	case "math annotation-xml":
+		for _, a := range n.Attr {
+			if a.Key == "encoding" && (strings.EqualFold(a.Val, "text/html") || strings.EqualFold(a.Val, "application/xhtml+xml")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// children writes the children of n one after the other
+func (r *renderer) children(n *html.Node) {
+	literal := r.literal(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if literal && c.Type == html.TextNode {
+			r.write(c.Data)
+		} else {
+			r.node(c)
+		}
+	}
+}
+
+// startTag writes the start tag of the element n, closing it if n is a void element
+func (r *renderer) startTag(n *html.Node) {
+	attrs := n.Attr
+	if r.opts.SortAttrs {
+		attrs = slices.Clone(attrs)
+		slices.SortStableFunc(attrs, func(a, b html.Attribute) int {
+			return strings.Compare(attrName(a), attrName(b))
+		})
+	}
+	r.write("<" + r.tagName(n))
+	for _, a := range attrs {
+		r.write(" " + attrName(a) + `="`)
+		r.escape(a.Val)
+		r.write(`"`)
+	}
+	if !r.selfClosing(n) {
+		r.write(">")
+		return
+	}
+	if n.FirstChild != nil {
+		r.fail(fmt.Sprintf("void element <%s> has child nodes", n.Data))
+		return
+	}
+	switch r.opts.SelfClosing {
+	case SelfClosingNone:
+		if r.opts.XML {
+			r.write("/>")
+		} else {
+			r.write(">")
+		}
+	case SelfClosingSpace:
+		r.write(" />")
+	default:
+		r.write("/>")
+	}
+}
+
+// pretty writes n and everything below it indented depth levels deep
+func (r *renderer) pretty(n *html.Node, depth int) {
+	indent := strings.Repeat(r.opts.Indent, depth)
+	switch {
+	case n.Type == html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.pretty(c, depth)
+		}
+	case n.Type == html.TextNode && n.Namespace != cdataNamespace:
+		if s := strings.Trim(n.Data, asciiSpace); s != "" {
+			r.write(indent)
+			r.escape(s)
+			r.write("\n")
+		}
+	case n.Type != html.ElementNode || r.keepsLayout(n):
+		r.write(indent)
+		r.node(n)
+		r.write("\n")
+	default:
+		r.write(indent)
+		r.startTag(n)
+		r.write("\n")
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			r.pretty(c, depth+1)
+		}
+		r.write(indent + "</" + r.tagName(n) + ">\n")
+	}
+}
+
+// selfClosing reports whether the element n is written as a single tag
+func (r *renderer) selfClosing(n *html.Node) bool {
+	if r.opts.XML {
+		return n.FirstChild == nil
+	}
+	return voidElements[n.Data]
+}
+
+func (r *renderer) tagName(n *html.Node) string {
+	if r.prefixes && n.Namespace != "" {
+		return n.Namespace + ":" + n.Data
+	}
+	return n.Data
+}
+
+// keepsLayout reports whether the element n is written on a single line
+// when pretty-printing. In XML, these are the elements holding only text
+func (r *renderer) keepsLayout(n *html.Node) bool {
+	if r.opts.XML {
+		return firstElementChild(n) == nil
+	}
+	if voidElements[n.Data] || n.Namespace == "" && literalElements[n.Data] {
+		return true
+	}
+	switch n.Data {
+	case "pre", "listing", "textarea":
+		return true
+	}
+	return false
+}
+
+func (r *renderer) fail(msg string) {
+	if r.err != nil {
+		return
+	}
+	if debug {
+		panic("Unable to render the HTML: " + msg)
+	}
+	r.err = newError(ErrUnableToRender, msg)
+}
+
+// writeCounter lets html.Render write through a renderer
+type writeCounter struct {
+	r *renderer
+}
+
+func (w writeCounter) Write(p []byte) (int, error) {
+	if w.r.done {
+		return len(p), nil
+	}
+	n, err := w.r.w.Write(p)
+	w.r.n += int64(n)
+	return n, err
+}
+
+func attrName(a html.Attribute) string {
+	if a.Namespace != "" {
+		return a.Namespace + ":" + a.Key
+	}
+	return a.Key
+}
+
+// render writes out the pointer as described by opts, returning the number of bytes written
+func (r Root) render(w io.Writer, opts FormatOptions) (int64, error) {
+	rr := &renderer{w: w, opts: opts}
+	if r.Pointer == nil {
+		rr.fail("nothing to render")
+		return rr.n, rr.err
+	}
+	if inXMLDocument(r.Pointer) {
+		// HTML can't have elements such as an RSS <link> with content
+		rr.opts.XML, rr.prefixes = true, true
+	}
+	if opts.Pretty {
+		rr.pretty(r.Pointer, 0)
+	} else {
+		rr.node(r.Pointer)
+	}
+	return rr.n, rr.err
+}
+
+// Format writes out the HTML code for the pointer as described by opts
+func (r Root) Format(w io.Writer, opts FormatOptions) error {
+	_, err := r.render(w, opts)
+	return err
+}
+
+// WriteTo writes out the HTML code for the pointer, like HTML does,
+// without building it up in memory first
+func (r Root) WriteTo(w io.Writer) (int64, error) {
+	return r.render(w, FormatOptions{})
+}
+
+// Prettify returns the HTML code for the pointer with every tag and
+// string on a line of its own, indented by indent once per level
+func (r Root) Prettify(indent string) (string, error) {
+	var buf strings.Builder
+	err := r.Format(&buf, FormatOptions{Pretty: true, Indent: indent})
+	return buf.String(), err
+}
+
+// InnerHTML returns the HTML code for the children of the pointer, leaving out its own tags
+func (r Root) InnerHTML() (string, error) {
+	var buf strings.Builder
+	rr := &renderer{w: &buf}
+	if r.Pointer == nil {
+		rr.fail("nothing to render")
+	} else {
+		rr.opts.XML = inXMLDocument(r.Pointer)
+		rr.prefixes = rr.opts.XML
+		rr.children(r.Pointer)
+	}
+	if rr.err != nil {
+		return "", rr.err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,115 @@
+package soup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+const renderHTML = `<!DOCTYPE html><html><head><title>Café &amp; "bar"</title><style>a > b { color: red }</style></head>` +
+	`<body><!-- note --><div id="main" class="box" data-x='it&#39;s'><p>One<br>two</p><pre>
+  keep   this</pre><img src="a.png" alt="A"></div></body></html>`
+
+func TestFormat(t *testing.T) {
+	page := HTMLParse(renderHTML)
+	var buf strings.Builder
+	assert.NoError(t, page.Format(&buf, FormatOptions{}))
+	assert.Equal(t, page.HTML(), buf.String())
+
+	// the document node as well as the element HTMLParse points to
+	var want strings.Builder
+	html.Render(&want, page.Pointer.Parent)
+	buf.Reset()
+	assert.NoError(t, Root{Pointer: page.Pointer.Parent}.Format(&buf, FormatOptions{}))
+	assert.Equal(t, want.String(), buf.String())
+
+	div := page.Find("div")
+	buf.Reset()
+	assert.NoError(t, div.Find("img").Format(&buf, FormatOptions{SortAttrs: true, SelfClosing: SelfClosingNone}))
+	assert.Equal(t, `<img alt="A" src="a.png">`, buf.String())
+	buf.Reset()
+	assert.NoError(t, div.Find("br").Format(&buf, FormatOptions{SelfClosing: SelfClosingSpace}))
+	assert.Equal(t, `<br />`, buf.String())
+	buf.Reset()
+	assert.NoError(t, page.Find("title").Format(&buf, FormatOptions{Escape: EscapeFull}))
+	assert.Equal(t, `<title>Caf&#233; &amp; &#34;bar&#34;</title>`, buf.String())
+
+	assert.Equal(t, ErrUnableToRender, Root{}.Format(&buf, FormatOptions{}).(Error).Type)
+	img := div.Find("img")
+	img.Pointer.AppendChild(&html.Node{Type: html.TextNode, Data: "oops"})
+	assert.Equal(t, ErrUnableToRender, div.Format(&buf, FormatOptions{}).(Error).Type)
+}
+
+func TestFormatRawText(t *testing.T) {
+	for _, src := range []string{
+		`<script>if (a < b && c > "d") {}</script><style>a > b { content: "&amp;" }</style>`,
+		`<textarea>
+ <b>&lt;</textarea><title>a < b</title><pre>
+
+x</pre><listing>
+y</listing>`,
+		`<xmp><b>&amp;</b></xmp><iframe><p></iframe><noembed><p></noembed><noframes><p></noframes><noscript><p>&</noscript>`,
+		`<svg><style>a < b</style><foreignObject><style>a < b</style></foreignObject></svg>`,
+		`<math><annotation-xml encoding="text/html"><style>a < b</style></annotation-xml></math>`,
+		`<div><p>before</p><plaintext>a < b </plaintext> & <b>c</b></div><p>after</p>`,
+	} {
+		doc := HTMLParse(src).Pointer.Parent
+		var want strings.Builder
+		assert.NoError(t, html.Render(&want, doc))
+		var buf strings.Builder
+		assert.NoError(t, Root{Pointer: doc}.Format(&buf, FormatOptions{}), src)
+		assert.Equal(t, want.String(), buf.String(), src)
+	}
+
+	// nothing is written after <plaintext>, or it would be read back as its text
+	page := HTMLParse(`<div><plaintext>a < b</div><p>after</p>`)
+	var buf strings.Builder
+	n, err := page.Find("body").WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "<body><div><plaintext>a < b</div><p>after</p>", buf.String())
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, page.Find("body").HTML(), buf.String())
+}
+
+func TestWriteToAndInnerHTML(t *testing.T) {
+	page := HTMLParse(renderHTML)
+	var buf strings.Builder
+	n, err := page.Find("p").WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len("<p>One<br/>two</p>")), n)
+	assert.Equal(t, "<p>One<br/>two</p>", buf.String())
+
+	inner, err := page.Find("p").InnerHTML()
+	assert.NoError(t, err)
+	assert.Equal(t, "One<br/>two", inner)
+	inner, err = page.Find("style").InnerHTML()
+	assert.NoError(t, err)
+	assert.Equal(t, "a > b { color: red }", inner)
+	_, err = Root{}.InnerHTML()
+	assert.Equal(t, ErrUnableToRender, err.(Error).Type)
+}
+
+func TestPrettify(t *testing.T) {
+	page := HTMLParse(renderHTML)
+	out, err := page.Find("body").Prettify("  ")
+	assert.NoError(t, err)
+	assert.Equal(t, `<body>
+  <!-- note -->
+  <div id="main" class="box" data-x="it&#39;s">
+    <p>
+      One
+      <br/>
+      two
+    </p>
+    <pre>  keep   this</pre>
+    <img src="a.png" alt="A"/>
+  </div>
+</body>
+`, out)
+
+	out, err = Root{Pointer: page.Pointer.Parent}.Prettify("\t")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "<!DOCTYPE html>\n<html>\n\t<head>\n\t\t<title>\n\t\t\tCafé &amp; &#34;bar&#34;\n\t\t</title>\n\t\t<style>a > b { color: red }</style>\n"))
+}
@@ -0,0 +1,991 @@
+package soup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled CSS selector. Compiling a selector once and
+// reusing it avoids parsing it again for every document, and a Selector
+// is safe for concurrent use by multiple goroutines
+type Selector struct {
+	source string
+	list   selectorList
+}
+
+// Compile parses a CSS selector into a Selector that can be matched
+// against any number of documents
+func Compile(selector string) (*Selector, error) {
+	list, err := parseSelectorList(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &Selector{source: selector, list: list}, nil
+}
+
+// MustCompile is like Compile but panics if the selector cannot be parsed.
+// It is meant for selectors held in global variables
+func MustCompile(selector string) *Selector {
+	s, err := Compile(selector)
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+// String returns the source text of the selector
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match reports whether the element r points to matches the selector
+func (s *Selector) Match(r Root) bool {
+	return r.Pointer != nil && s.list.match(r.Pointer)
+}
+
+// Filter returns the elements in roots that match the selector
+func (s *Selector) Filter(roots []Root) []Root {
+	matched := make([]Root, 0, len(roots))
+	for _, r := range roots {
+		if s.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// First returns the first element below r matching the selector
+func (s *Selector) First(r Root) Root {
+	temp, ok := matchOnce(selectionRoot(r.Pointer), s.list.match)
+	if !ok {
+		if debug {
+			panic("Element matching selector `" + s.source + "` not found")
+		}
+		return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("element matching selector `%s` not found%s", s.source, searchedFrom(r.Pointer)))}
+	}
+	return Root{Pointer: temp, NodeValue: temp.Data}
+}
+
+// All returns all elements below r matching the selector, in document order
+func (s *Selector) All(r Root) []Root {
+	temp := matchAll(selectionRoot(r.Pointer), s.list.match, FindOptions{})
+	if len(temp) == 0 {
+		if debug {
+			panic("Element matching selector `" + s.source + "` not found")
+		}
+		return []Root{}
+	}
+	pointers := make([]Root, 0, len(temp))
+	for i := 0; i < len(temp); i++ {
+		pointers = append(pointers, Root{Pointer: temp[i], NodeValue: temp[i].Data})
+	}
+	return pointers
+}
+
+// Select returns all elements below r matching the given CSS selector,
+// in document order. An invalid selector gives an empty slice, the same
+// as a selector matching nothing; SelectAll reports it
+func (r Root) Select(selector string) []Root {
+	roots, _ := r.SelectAll(selector)
+	return roots
+}
+
+// SelectAll is like Select, but returns an ErrInvalidSelector error
+// for an invalid selector
+func (r Root) SelectAll(selector string) ([]Root, error) {
+	sel, err := Compile(selector)
+	if err != nil {
+		if debug {
+			panic("Invalid selector `" + selector + "`")
+		}
+		return []Root{}, err
+	}
+	return sel.All(r), nil
+}
+
+// SelectOne returns the first element below r matching the given CSS selector
+func (r Root) SelectOne(selector string) Root {
+	sel, err := Compile(selector)
+	if err != nil {
+		if debug {
+			panic("Invalid selector `" + selector + "`")
+		}
+		return Root{Error: err}
+	}
+	return sel.First(r)
+}
+
+// selectionRoot returns the node below which selectors look for elements
+// when r is the pointer: the document itself when r is its root element,
+// as HTMLParse returns, so that "html" and ":root" can match it
+func selectionRoot(n *html.Node) *html.Node {
+	if n != nil && n.Type == html.ElementNode && n.Parent != nil && n.Parent.Type == html.DocumentNode {
+		return n.Parent
+	}
+	return n
+}
+
+// selectorList is a comma separated group of selectors, matching
+// an element when any one of them does
+type selectorList []complexSelector
+
+func (l selectorList) match(n *html.Node) bool {
+	for _, c := range l {
+		if c.match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// complexSelector is a chain of compound selectors joined by combinators,
+// stored left to right as written in the selector
+type complexSelector []selectorPart
+
+// selectorPart is a compound selector together with the combinator
+// joining it to the part before it, which is 0 for the first part
+type selectorPart struct {
+	combinator byte
+	compound   compoundSelector
+}
+
+func (c complexSelector) match(n *html.Node) bool {
+	return c.matchPart(len(c)-1, n, nil)
+}
+
+// matchPart checks n against part i and, walking right to left,
+// the parts before it. For a relative selector, as used in :has(),
+// scope is the element the first part's combinator is relative to
+func (c complexSelector) matchPart(i int, n, scope *html.Node) bool {
+	if !c[i].compound.match(n) {
+		return false
+	}
+	if i == 0 {
+		return scope == nil || walkCombinator(c[0].combinator, n, func(p *html.Node) bool {
+			return p == scope
+		})
+	}
+	return walkCombinator(c[i].combinator, n, func(p *html.Node) bool {
+		return c.matchPart(i-1, p, scope)
+	})
+}
+
+// walkCombinator visits the elements that n can be reached from through
+// the given combinator, reporting whether f holds for any of them
+func walkCombinator(combinator byte, n *html.Node, f func(*html.Node) bool) bool {
+	switch combinator {
+	case ' ':
+		for p := parentElement(n); p != nil; p = parentElement(p) {
+			if f(p) {
+				return true
+			}
+		}
+	case '>':
+		if p := parentElement(n); p != nil {
+			return f(p)
+		}
+	case '+':
+		if p := prevElementSibling(n); p != nil {
+			return f(p)
+		}
+	case '~':
+		for p := prevElementSibling(n); p != nil; p = prevElementSibling(p) {
+			if f(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compoundSelector is a sequence of simple selectors which must all match
+type compoundSelector []simpleSelector
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, s := range c {
+		if !s.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+// simpleSelector is a single condition on an element, such as
+// its tag name, an attribute or a pseudo-class
+type simpleSelector interface {
+	match(n *html.Node) bool
+}
+
+// anyNamespace is used for a namespace prefix that was left out or given as `*`
+const anyNamespace = "*"
+
+// typeSelector matches the tag name of an element, or any name when name is empty
+type typeSelector struct {
+	namespace string
+	name      string
+	// unprefixed is set when the selector has no namespace prefix at all
+	unprefixed bool
+}
+
+func (s typeSelector) match(n *html.Node) bool {
+	if s.namespace != anyNamespace && s.namespace != n.Namespace {
+		return false
+	}
+	if s.unprefixed && s.name != "" && n.Namespace != "" && inXMLDocument(n) {
+		// as with Find and XPath, "link" doesn't match <atom:link> in
+		// XML, while *|link does
+		return false
+	}
+	return s.name == "" || matchTagName(n, s.name)
+}
+
+// attrSelector matches an attribute of an element using one of
+// the CSS attribute operators, or only its presence when op is empty
+type attrSelector struct {
+	namespace string
+	key       string
+	op        string
+	val       string
+	fold      bool
+}
+
+func (s attrSelector) match(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if s.namespace != anyNamespace && s.namespace != attr.Namespace {
+			continue
+		}
+		if !matchName(n, attr.Key, s.key) {
+			continue
+		}
+		if s.matchValue(attr.Val) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s attrSelector) matchValue(v string) bool {
+	want := s.val
+	if s.fold {
+		v, want = strings.ToLower(v), strings.ToLower(want)
+	}
+	switch s.op {
+	case "":
+		return true
+	case "=":
+		return v == want
+	case "~=":
+		if want == "" || strings.ContainsAny(want, " \t\n\r\f") {
+			return false
+		}
+		for _, f := range strings.Fields(v) {
+			if f == want {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return v == want || strings.HasPrefix(v, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(v, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(v, want)
+	case "*=":
+		return want != "" && strings.Contains(v, want)
+	}
+	return false
+}
+
+// nthSelector implements the :nth-* pseudo-classes, matching elements
+// whose position among their element siblings, counted from the end
+// if last is set and among those of the same type if ofType is set,
+// is a*n+b for some n >= 0
+type nthSelector struct {
+	a, b   int
+	last   bool
+	ofType bool
+}
+
+func (s nthSelector) match(n *html.Node) bool {
+	pos := 1
+	next := prevElementSibling
+	if s.last {
+		next = nextElementSibling
+	}
+	for c := next(n); c != nil; c = next(c) {
+		if !s.ofType || (c.Data == n.Data && c.Namespace == n.Namespace) {
+			pos++
+		}
+	}
+	if s.a == 0 {
+		return pos == s.b
+	}
+	return (pos-s.b)/s.a >= 0 && (pos-s.b)%s.a == 0
+}
+
+// emptySelector implements :empty, matching elements without
+// child elements or text
+type emptySelector struct{}
+
+func (emptySelector) match(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode || (c.Type == html.TextNode && c.Data != "") {
+			return false
+		}
+	}
+	return true
+}
+
+// rootSelector implements :root, matching the top element of the tree
+type rootSelector struct{}
+
+func (rootSelector) match(n *html.Node) bool {
+	return n.Parent == nil || n.Parent.Type == html.DocumentNode
+}
+
+// pseudoListSelector implements :is() and :where(), or :not() when
+// not is set, matching elements against a selector list
+type pseudoListSelector struct {
+	list selectorList
+	not  bool
+}
+
+func (s pseudoListSelector) match(n *html.Node) bool {
+	return s.list.match(n) != s.not
+}
+
+// hasSelector implements :has(), matching elements that the relative
+// selectors it contains can be anchored at
+type hasSelector selectorList
+
+func (s hasSelector) match(n *html.Node) bool {
+	for _, c := range s {
+		var found bool
+		visit := func(d *html.Node) bool {
+			found = d.Type == html.ElementNode && c.matchPart(len(c)-1, d, n)
+			return found
+		}
+		if c[0].combinator == ' ' || c[0].combinator == '>' {
+			walkDescendants(n, visit)
+		} else {
+			for sib := nextElementSibling(n); sib != nil && !found; sib = nextElementSibling(sib) {
+				if !visit(sib) {
+					walkDescendants(sib, visit)
+				}
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// containsSelector implements :-soup-contains(), matching elements whose
+// text, or only their own text if own is set, contains any of the values
+type containsSelector struct {
+	values []string
+	own    bool
+}
+
+func (s containsSelector) match(n *html.Node) bool {
+	var text string
+	if s.own {
+		text = ownText(n)
+	} else {
+		text = Root{Pointer: n}.FullText()
+	}
+	for _, v := range s.values {
+		if strings.Contains(text, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkDescendants calls f on each descendant of n in document order,
+// stopping as soon as it returns true
+func walkDescendants(n *html.Node, f func(*html.Node) bool) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if f(c) || walkDescendants(c, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTagName reports whether n has the given tag name, which like
+// in browsers is compared case-insensitively for HTML documents
+func matchTagName(n *html.Node, name string) bool {
+	return matchName(n, n.Data, name)
+}
+
+// matchName compares a tag or attribute name of n with name: exactly
+// when n is part of an XML document, case-insensitively otherwise
+func matchName(n *html.Node, a, b string) bool {
+	if a == b {
+		return true
+	}
+	return !inXMLDocument(n) && strings.EqualFold(a, b)
+}
+
+// parentElement returns the parent of n if it is an element
+func parentElement(n *html.Node) *html.Node {
+	if p := n.Parent; p != nil && p.Type == html.ElementNode {
+		return p
+	}
+	return nil
+}
+
+// prevElementSibling returns the closest preceding sibling of n which is an element
+func prevElementSibling(n *html.Node) *html.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+	}
+	return nil
+}
+
+// nextElementSibling returns the closest following sibling of n which is an element
+func nextElementSibling(n *html.Node) *html.Node {
+	for p := n.NextSibling; p != nil; p = p.NextSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+	}
+	return nil
+}
+
+// selectorParser is a recursive descent parser for CSS selectors
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+// parseSelectorList parses a comma separated group of CSS selectors
+func parseSelectorList(s string) (selectorList, error) {
+	p := &selectorParser{s: s}
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos])
+	}
+	return list, nil
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) error {
+	return newError(ErrInvalidSelector, fmt.Sprintf("invalid selector `%s`: %s at offset %d", p.s, fmt.Sprintf(format, args...), p.pos))
+}
+
+func (p *selectorParser) parseList() (selectorList, error) {
+	var list selectorList
+	for {
+		p.skipWhitespace()
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+		p.skipWhitespace()
+		if !p.consume(',') {
+			return list, nil
+		}
+	}
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	compound, err := p.parseCompound()
+	if err != nil {
+		return nil, err
+	}
+	sel := complexSelector{{compound: compound}}
+	for {
+		combinator, ok := p.parseCombinator()
+		if !ok {
+			return sel, nil
+		}
+		compound, err := p.parseCompound()
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, selectorPart{combinator: combinator, compound: compound})
+	}
+}
+
+// parseCombinator consumes a combinator if one follows, treating
+// whitespace not followed by the end of a selector as a descendant combinator
+func (p *selectorParser) parseCombinator() (byte, bool) {
+	start := p.pos
+	sawSpace := p.skipWhitespace()
+	if p.pos >= len(p.s) {
+		p.pos = start
+		return 0, false
+	}
+	switch c := p.s[p.pos]; c {
+	case '>', '+', '~':
+		p.pos++
+		p.skipWhitespace()
+		return c, true
+	case ',', ')':
+		p.pos = start
+		return 0, false
+	}
+	if sawSpace {
+		return ' ', true
+	}
+	return 0, false
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var compound compoundSelector
+	if t, ok, err := p.parseTypeSelector(); err != nil {
+		return nil, err
+	} else if ok {
+		compound = append(compound, t)
+	}
+	for p.pos < len(p.s) {
+		var s simpleSelector
+		var err error
+		switch p.s[p.pos] {
+		case '#':
+			p.pos++
+			var name string
+			if name, err = p.parseName(); err == nil {
+				s = attrSelector{namespace: anyNamespace, key: "id", op: "=", val: name}
+			}
+		case '.':
+			p.pos++
+			var name string
+			if name, err = p.parseIdent(); err == nil {
+				s = attrSelector{namespace: anyNamespace, key: "class", op: "~=", val: name}
+			}
+		case '[':
+			p.pos++
+			s, err = p.parseAttr()
+		case ':':
+			p.pos++
+			s, err = p.parsePseudo()
+		default:
+			if len(compound) == 0 {
+				return nil, p.errorf("expected selector")
+			}
+			return compound, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		compound = append(compound, s)
+	}
+	if len(compound) == 0 {
+		return nil, p.errorf("expected selector")
+	}
+	return compound, nil
+}
+
+// parseTypeSelector parses an optional type or universal selector,
+// including a namespace prefix
+func (p *selectorParser) parseTypeSelector() (simpleSelector, bool, error) {
+	if p.pos >= len(p.s) {
+		return nil, false, nil
+	}
+	c := p.s[p.pos]
+	if c != '*' && c != '|' && !p.startsIdent() {
+		return nil, false, nil
+	}
+	namespace, unprefixed := anyNamespace, true
+	name, err := p.parseNameOrStar()
+	if err != nil {
+		return nil, false, err
+	}
+	if p.peek('|') && !p.peekAt(1, '=') {
+		p.pos++
+		unprefixed = false
+		namespace = name
+		if name == "*" {
+			namespace = anyNamespace
+		}
+		if name, err = p.parseNameOrStar(); err != nil {
+			return nil, false, err
+		}
+	}
+	if name == "*" {
+		name = ""
+	}
+	return typeSelector{namespace: namespace, name: name, unprefixed: unprefixed}, true, nil
+}
+
+// parseNameOrStar parses an identifier or `*`, returning an empty
+// string for the missing prefix in `|name`
+func (p *selectorParser) parseNameOrStar() (string, error) {
+	if p.consume('*') {
+		return "*", nil
+	}
+	if p.peek('|') {
+		return "", nil
+	}
+	return p.parseIdent()
+}
+
+func (p *selectorParser) parseAttr() (simpleSelector, error) {
+	p.skipWhitespace()
+	sel := attrSelector{namespace: anyNamespace}
+	name, err := p.parseNameOrStar()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek('|') && !p.peekAt(1, '=') {
+		p.pos++
+		if name != "*" {
+			sel.namespace = name
+		}
+		if name, err = p.parseIdent(); err != nil {
+			return nil, err
+		}
+	} else if name == "*" || name == "" {
+		return nil, p.errorf("expected attribute name")
+	}
+	sel.key = name
+	p.skipWhitespace()
+	if p.consume(']') {
+		return sel, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			sel.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if sel.op == "" {
+		return nil, p.errorf("expected attribute operator")
+	}
+	p.skipWhitespace()
+	if p.peek('"') || p.peek('\'') {
+		sel.val, err = p.parseString()
+	} else {
+		sel.val, err = p.parseIdent()
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if p.consume('i') || p.consume('I') {
+		sel.fold = true
+		p.skipWhitespace()
+	} else if p.consume('s') || p.consume('S') {
+		p.skipWhitespace()
+	}
+	if !p.consume(']') {
+		return nil, p.errorf("expected `]`")
+	}
+	return sel, nil
+}
+
+func (p *selectorParser) parsePseudo() (simpleSelector, error) {
+	if p.peek(':') {
+		return nil, p.errorf("pseudo-elements are not supported")
+	}
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+	switch name {
+	case "first-child":
+		return nthSelector{a: 0, b: 1}, nil
+	case "last-child":
+		return nthSelector{a: 0, b: 1, last: true}, nil
+	case "only-child":
+		return compoundSelector{nthSelector{a: 0, b: 1}, nthSelector{a: 0, b: 1, last: true}}, nil
+	case "first-of-type":
+		return nthSelector{a: 0, b: 1, ofType: true}, nil
+	case "last-of-type":
+		return nthSelector{a: 0, b: 1, last: true, ofType: true}, nil
+	case "only-of-type":
+		return compoundSelector{nthSelector{a: 0, b: 1, ofType: true}, nthSelector{a: 0, b: 1, last: true, ofType: true}}, nil
+	case "empty":
+		return emptySelector{}, nil
+	case "root":
+		return rootSelector{}, nil
+	}
+	if !p.consume('(') {
+		return nil, p.errorf("unsupported pseudo-class `:%s`", name)
+	}
+	var sel simpleSelector
+	switch name {
+	case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+		nth := nthSelector{
+			last:   strings.HasPrefix(name, "nth-last-"),
+			ofType: strings.HasSuffix(name, "-of-type"),
+		}
+		nth.a, nth.b, err = p.parseNth()
+		sel = nth
+	case "not", "is", "where", "matches":
+		var list selectorList
+		list, err = p.parseList()
+		sel = pseudoListSelector{list: list, not: name == "not"}
+	case "has":
+		var list selectorList
+		list, err = p.parseRelativeList()
+		sel = hasSelector(list)
+	case "-soup-contains", "-soup-contains-own", "contains":
+		var values []string
+		values, err = p.parseStringList()
+		sel = containsSelector{values: values, own: name == "-soup-contains-own"}
+	default:
+		return nil, p.errorf("unsupported pseudo-class `:%s()`", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if !p.consume(')') {
+		return nil, p.errorf("expected `)`")
+	}
+	return sel, nil
+}
+
+// parseRelativeList parses the argument of :has(), a selector list
+// whose selectors may start with a combinator and default to the
+// descendant combinator when they don't
+func (p *selectorParser) parseRelativeList() (selectorList, error) {
+	var list selectorList
+	for {
+		p.skipWhitespace()
+		combinator := byte(' ')
+		if p.pos < len(p.s) && strings.IndexByte(">+~", p.s[p.pos]) >= 0 {
+			combinator = p.s[p.pos]
+			p.pos++
+			p.skipWhitespace()
+		}
+		c, err := p.parseComplex()
+		if err != nil {
+			return nil, err
+		}
+		c[0].combinator = combinator
+		list = append(list, c)
+		p.skipWhitespace()
+		if !p.consume(',') {
+			return list, nil
+		}
+	}
+}
+
+// nthPattern matches the an+b argument of the :nth-* pseudo-classes
+var nthPattern = regexp.MustCompile(`^(?:([+-]?\d*)n(?:\s*([+-])\s*(\d+))?|([+-]?\d+))$`)
+
+// parseNth parses the an+b argument of the :nth-* pseudo-classes
+func (p *selectorParser) parseNth() (int, int, error) {
+	end := strings.IndexByte(p.s[p.pos:], ')')
+	if end < 0 {
+		return 0, 0, p.errorf("expected `)`")
+	}
+	arg := strings.ToLower(strings.TrimSpace(p.s[p.pos : p.pos+end]))
+	var a, b int
+	switch arg {
+	case "odd":
+		a, b = 2, 1
+	case "even":
+		a, b = 2, 0
+	default:
+		m := nthPattern.FindStringSubmatch(arg)
+		if m == nil {
+			return 0, 0, p.errorf("invalid argument `%s`", arg)
+		}
+		if m[4] != "" {
+			b, _ = strconv.Atoi(m[4])
+			break
+		}
+		switch m[1] {
+		case "", "+":
+			a = 1
+		case "-":
+			a = -1
+		default:
+			a, _ = strconv.Atoi(m[1])
+		}
+		if m[3] != "" {
+			b, _ = strconv.Atoi(m[3])
+			if m[2] == "-" {
+				b = -b
+			}
+		}
+	}
+	p.pos += end
+	return a, b, nil
+}
+
+// parseStringList parses a comma separated list of strings or identifiers
+func (p *selectorParser) parseStringList() ([]string, error) {
+	var values []string
+	for {
+		p.skipWhitespace()
+		var v string
+		var err error
+		if p.peek('"') || p.peek('\'') {
+			v, err = p.parseString()
+		} else {
+			v, err = p.parseIdent()
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p.skipWhitespace()
+		if !p.consume(',') {
+			return values, nil
+		}
+	}
+}
+
+// parseIdent parses a CSS identifier, resolving escapes
+func (p *selectorParser) parseIdent() (string, error) {
+	if !p.startsIdent() {
+		return "", p.errorf("expected identifier")
+	}
+	return p.parseName()
+}
+
+// parseName parses a sequence of CSS name characters, as used
+// for identifiers and the value of an id selector
+func (p *selectorParser) parseName() (string, error) {
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case isNameByte(c):
+			b.WriteByte(c)
+			p.pos++
+		default:
+			if b.Len() == 0 {
+				return "", p.errorf("expected name")
+			}
+			return b.String(), nil
+		}
+	}
+	if b.Len() == 0 {
+		return "", p.errorf("expected name")
+	}
+	return b.String(), nil
+}
+
+// startsIdent reports whether an identifier starts at the current position
+func (p *selectorParser) startsIdent() bool {
+	i := p.pos
+	if i < len(p.s) && p.s[i] == '-' {
+		i++
+	}
+	if i >= len(p.s) {
+		return false
+	}
+	c := p.s[i]
+	return c == '\\' || c == '-' || c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isNameByte(c byte) bool {
+	return c == '-' || c == '_' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// parseEscape parses a backslash escape, either up to six hex digits
+// followed by optional whitespace, or a single escaped character
+func (p *selectorParser) parseEscape() (rune, error) {
+	p.pos++
+	if p.pos >= len(p.s) {
+		return 0, p.errorf("unterminated escape")
+	}
+	start := p.pos
+	for p.pos < len(p.s) && p.pos-start < 6 && isHexByte(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos > start {
+		v, _ := strconv.ParseUint(p.s[start:p.pos], 16, 32)
+		if p.pos < len(p.s) && isSpaceByte(p.s[p.pos]) {
+			p.pos++
+		}
+		if v == 0 || v > utf8.MaxRune || (0xD800 <= v && v <= 0xDFFF) {
+			return utf8.RuneError, nil
+		}
+		return rune(v), nil
+	}
+	r, size := utf8.DecodeRuneInString(p.s[p.pos:])
+	p.pos += size
+	return r, nil
+}
+
+func isHexByte(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// parseString parses a single or double quoted string
+func (p *selectorParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\\' && p.pos+1 < len(p.s) && p.s[p.pos+1] == '\n':
+			p.pos += 2
+		case c == '\\':
+			r, err := p.parseEscape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// skipWhitespace skips any whitespace, reporting whether there was some
+func (p *selectorParser) skipWhitespace() bool {
+	start := p.pos
+	for p.pos < len(p.s) && isSpaceByte(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) peek(c byte) bool {
+	return p.peekAt(0, c)
+}
+
+func (p *selectorParser) peekAt(offset int, c byte) bool {
+	return p.pos+offset < len(p.s) && p.s[p.pos+offset] == c
+}
+
+func (p *selectorParser) consume(c byte) bool {
+	if p.peek(c) {
+		p.pos++
+		return true
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package soup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const selectHTML = `
+<html>
+  <body>
+    <div id="main" class="content wide">
+      <h2 lang="en-US">Heading</h2>
+      <p class="intro">First</p>
+      <p>Second <a href="https://example.com/a.pdf">secure</a></p>
+      <p>Third <a href="http://example.org/b.html">plain</a></p>
+    </div>
+    <ul data-kind="menu list">
+      <li>One</li>
+      <li class="active">Two</li>
+      <li>Three</li>
+    </ul>
+  </body>
+</html>
+`
+
+var selectDoc = HTMLParse(selectHTML)
+
+func selectedText(roots []Root) []string {
+	texts := make([]string, 0, len(roots))
+	for _, r := range roots {
+		texts = append(texts, r.FullText())
+	}
+	return texts
+}
+
+func TestSelectTypeClassAndID(t *testing.T) {
+	assert.Equal(t, []string{"First"}, selectedText(selectDoc.Select("p.intro")))
+	assert.Equal(t, 3, len(selectDoc.Select("#main p")))
+	assert.Equal(t, 1, len(selectDoc.Select("div.content.wide")))
+	assert.Equal(t, 0, len(selectDoc.Select("div.content.narrow")))
+	assert.Equal(t, "Heading", selectDoc.SelectOne("DIV > H2").Text())
+}
+
+func TestSelectAttributeOperators(t *testing.T) {
+	assert.Equal(t, []string{"secure"}, selectedText(selectDoc.Select(`a[href^="https://"]`)))
+	assert.Equal(t, []string{"plain"}, selectedText(selectDoc.Select(`a[href$=".html"]`)))
+	assert.Equal(t, []string{"secure", "plain"}, selectedText(selectDoc.Select(`a[href*=example]`)))
+	assert.Equal(t, 1, len(selectDoc.Select(`ul[data-kind~=list]`)))
+	assert.Equal(t, 1, len(selectDoc.Select(`h2[lang|=en]`)))
+	assert.Equal(t, 1, len(selectDoc.Select(`h2[LANG="EN-us" i]`)))
+	assert.Equal(t, 3, len(selectDoc.Select(`[class]`)))
+}
+
+func TestSelectCombinatorsAndGroups(t *testing.T) {
+	assert.Equal(t, []string{"Second secure"}, selectedText(selectDoc.Select("p.intro + p")))
+	assert.Equal(t, []string{"Second secure", "Third plain"}, selectedText(selectDoc.Select("p.intro ~ p")))
+	assert.Equal(t, []string{"Three"}, selectedText(selectDoc.Select("li.active+li")))
+	assert.Equal(t, 0, len(selectDoc.Select("body > p")))
+	assert.Equal(t, []string{"Heading", "Two"}, selectedText(selectDoc.Select("li.active, h2")))
+}
+
+func TestSelectScopedToRoot(t *testing.T) {
+	ul := selectDoc.SelectOne("ul")
+	assert.Equal(t, 3, len(ul.Select("li")))
+	assert.Equal(t, 0, len(ul.Select("p")))
+	assert.Equal(t, 3, len(ul.Select("body li")))
+}
+
+func TestSelectDocumentElement(t *testing.T) {
+	assert.Equal(t, "html", selectDoc.SelectOne(":root").NodeValue)
+	html := selectDoc.Select("html")
+	if assert.Len(t, html, 1) {
+		assert.Equal(t, "html", html[0].NodeValue)
+	}
+	assert.Equal(t, "html", selectDoc.SelectOne("html:has(#main)").NodeValue)
+	assert.Empty(t, selectDoc.SelectOne("body").Select("html"))
+}
+
+func TestSelectOneNotFound(t *testing.T) {
+	r := selectDoc.SelectOne("table td")
+	assert.IsType(t, Error{}, r.Error)
+	assert.Equal(t, ErrElementNotFound, r.Error.(Error).Type)
+	assert.Nil(t, r.Pointer)
+
+	missing := selectDoc.SelectOne("table").SelectOne("td")
+	assert.Equal(t, ErrElementNotFound, missing.Error.(Error).Type)
+	assert.Empty(t, selectDoc.SelectOne("table").Select("td"))
+}
+
+func TestSelectInvalidSelector(t *testing.T) {
+	for _, selector := range []string{"", "p >", "a[href", "a[href=]", "p,,a", "p::before", "p:unknown", "li:nth-child(2 n)", "p:not()", "p:has(> )"} {
+		r := selectDoc.SelectOne(selector)
+		if assert.IsType(t, Error{}, r.Error, selector) {
+			assert.Equal(t, ErrInvalidSelector, r.Error.(Error).Type, selector)
+		}
+		assert.Empty(t, selectDoc.Select(selector), selector)
+		roots, err := selectDoc.SelectAll(selector)
+		assert.Empty(t, roots, selector)
+		if assert.IsType(t, Error{}, err, selector) {
+			assert.Equal(t, ErrInvalidSelector, err.(Error).Type, selector)
+		}
+	}
+}
+
+func TestSelectAll(t *testing.T) {
+	roots, err := selectDoc.SelectAll("li.active")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Two"}, selectedText(roots))
+
+	// no match is told apart from an invalid selector by the error
+	roots, err = selectDoc.SelectAll("table td")
+	assert.NoError(t, err)
+	assert.Empty(t, roots)
+	_, err = selectDoc.SelectAll("table td >")
+	assert.Equal(t, ErrInvalidSelector, err.(Error).Type)
+}
+
+func TestSelectStructuralPseudoClasses(t *testing.T) {
+	assert.Equal(t, []string{"One"}, selectedText(selectDoc.Select("li:first-child")))
+	assert.Equal(t, []string{"Three"}, selectedText(selectDoc.Select("li:last-child")))
+	assert.Equal(t, []string{"One", "Three"}, selectedText(selectDoc.Select("li:nth-child(odd)")))
+	assert.Equal(t, []string{"Two"}, selectedText(selectDoc.Select("li:nth-child(2n)")))
+	assert.Equal(t, []string{"One", "Two"}, selectedText(selectDoc.Select("li:nth-child(-n + 2)")))
+	assert.Equal(t, []string{"Three"}, selectedText(selectDoc.Select("li:nth-last-child(1)")))
+	assert.Equal(t, []string{"First"}, selectedText(selectDoc.Select("#main p:first-of-type")))
+	assert.Equal(t, []string{"Third plain"}, selectedText(selectDoc.Select("#main p:last-of-type")))
+	assert.Equal(t, []string{"Second secure"}, selectedText(selectDoc.Select("#main p:nth-of-type(2)")))
+	assert.Equal(t, []string{"Heading"}, selectedText(selectDoc.Select("#main > :only-of-type")))
+	assert.Equal(t, 2, len(selectDoc.Select("a:only-child")))
+	assert.Equal(t, "body", selectDoc.SelectOne(":root > body").NodeValue)
+	assert.Equal(t, "head", selectDoc.SelectOne(":empty").NodeValue)
+}
+
+func TestSelectLogicalPseudoClasses(t *testing.T) {
+	assert.Equal(t, []string{"One", "Three"}, selectedText(selectDoc.Select("li:not(.active)")))
+	assert.Equal(t, []string{"Heading", "First"}, selectedText(selectDoc.Select("#main :is(h2, .intro)")))
+	assert.Equal(t, []string{"Second secure", "Third plain"}, selectedText(selectDoc.Select("p:has(> a)")))
+	assert.Equal(t, []string{"First"}, selectedText(selectDoc.Select("p:has(+ p a[href^=https])")))
+	assert.Equal(t, 1, len(selectDoc.Select("div:has(h2 ~ p a)")))
+	assert.Equal(t, 0, len(selectDoc.Select("ul:has(> p)")))
+}
+
+func TestSelectContains(t *testing.T) {
+	assert.Equal(t, []string{"Third plain"}, selectedText(selectDoc.Select(`p:-soup-contains("plain")`)))
+	assert.Equal(t, []string{"Two", "Three"}, selectedText(selectDoc.Select(`li:-soup-contains("Tw", 'Thr')`)))
+	assert.Equal(t, 0, len(selectDoc.Select(`p:-soup-contains-own("plain")`)))
+	assert.Equal(t, []string{"Third plain"}, selectedText(selectDoc.Select(`p:-soup-contains-own("Third")`)))
+}
+
+func TestCompiledSelector(t *testing.T) {
+	sel := MustCompile("li.active, p.intro")
+	assert.Equal(t, "li.active, p.intro", sel.String())
+	assert.Equal(t, []string{"First", "Two"}, selectedText(sel.All(selectDoc)))
+	assert.Equal(t, "First", sel.First(selectDoc).Text())
+	assert.True(t, sel.Match(selectDoc.Find("li", "class", "active")))
+	assert.False(t, sel.Match(selectDoc.Find("li")))
+	assert.False(t, sel.Match(selectDoc.Find("missing")))
+	assert.Equal(t, []string{"Two"}, selectedText(sel.Filter(selectDoc.FindAll("li"))))
+
+	_, err := Compile("li:nth-child(")
+	assert.Equal(t, ErrInvalidSelector, err.(Error).Type)
+	assert.Panics(t, func() { MustCompile("[") })
+}
+
+func TestCompiledSelectorConcurrentUse(t *testing.T) {
+	sel := MustCompile("#main p:has(a):not(.intro)")
+	done := make(chan int)
+	for i := 0; i < 8; i++ {
+		go func() {
+			doc := HTMLParse(selectHTML)
+			done <- len(sel.All(doc))
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, 2, <-done)
+	}
+}
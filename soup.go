@@ -13,11 +13,9 @@ import (
 	"net/http"
 	"net/http/httputil"
 	netURL "net/url"
-	"regexp"
 	"strings"
 
 	"golang.org/x/net/html"
-	"golang.org/x/net/html/charset"
 )
 
 // ErrorType defines types of errors that are possible from soup
@@ -46,6 +44,31 @@ const (
 	ErrMarshallingPostRequest
 	// ErrReadingResponse will be returned if there was an error reading the response to our get request
 	ErrReadingResponse
+	// ErrInvalidSelector will be returned when a CSS selector could not be parsed
+	ErrInvalidSelector
+	// ErrInvalidXPath will be returned when an XPath expression could not be parsed or evaluated
+	ErrInvalidXPath
+	// ErrInvalidArguments will be returned when the arguments to a function
+	// are malformed. Functions returning several elements panic with it
+	ErrInvalidArguments
+	// ErrNoParent will be returned when no parent can be found
+	ErrNoParent
+	// ErrNoNextElement will be returned when no next element can be found in document order
+	ErrNoNextElement
+	// ErrNoPreviousElement will be returned when no previous element can be found in document order
+	ErrNoPreviousElement
+	// ErrInvalidModification will be returned when a change to the DOM cannot be made
+	ErrInvalidModification
+	// ErrAttributeNotFound will be returned when an element doesn't have the requested attribute
+	ErrAttributeNotFound
+	// ErrInvalidAttribute will be returned when the value of an attribute cannot be converted to the requested type
+	ErrInvalidAttribute
+	// ErrUnableToRender will be returned when the DOM cannot be written out as HTML
+	ErrUnableToRender
+	// ErrUnknownEncoding will be returned when a character encoding is not known
+	ErrUnknownEncoding
+	// ErrNoDocument will be returned when a node is not part of a parsed document
+	ErrNoDocument
 )
 
 // Error allows easier introspection on the type of error returned.
@@ -84,6 +107,10 @@ var (
 
 	// Cookies contains all HTTP cookies to send
 	Cookies = make(map[string]string)
+
+	// ForceEncoding, when set, is the encoding Get and GetWithClient decode
+	// responses from, instead of the one found by DetectEncoding
+	ForceEncoding string
 )
 
 // SetDebug sets the debug status
@@ -124,17 +151,30 @@ func GetWithClient(url string, client *http.Client) (string, error) {
 		return "", newError(ErrInGetRequest, "couldn't perform GET request to "+url)
 	}
 	defer resp.Body.Close()
-	utf8Body, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
-	if err != nil {
-		return "", err
-	}
-	bytes, err := ioutil.ReadAll(utf8Body)
+	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		if debug {
 			panic("Unable to read the response body")
 		}
 		return "", newError(ErrReadingResponse, "unable to read the response body")
 	}
+	label := ForceEncoding
+	if label == "" {
+		label = DetectEncoding(bytes, resp.Header.Get("Content-Type")).Encoding
+	}
+	enc, name, err := lookupEncoding(label)
+	if err != nil {
+		return "", err
+	}
+	bytes = bytes[byteOrderMark(bytes):]
+	if name != "utf-8" {
+		if bytes, err = enc.NewDecoder().Bytes(bytes); err != nil {
+			if debug {
+				panic("Unable to decode the response body from " + name)
+			}
+			return "", newError(ErrReadingResponse, "unable to decode the response body from "+name)
+		}
+	}
 	return string(bytes), nil
 }
 
@@ -247,6 +287,12 @@ func HTMLParse(s string) Root {
 		}
 		return Root{Error: newError(ErrUnableToParse, "unable to parse the HTML")}
 	}
+	return documentElement(r)
+}
+
+// documentElement returns a pointer to the <html> element of the parsed document doc
+func documentElement(doc *html.Node) Root {
+	r := doc
 	for r.Type != html.ElementNode {
 		switch r.Type {
 		case html.DocumentNode:
@@ -261,25 +307,48 @@ func HTMLParse(s string) Root {
 }
 
 // Find finds the first occurrence of the given tag name,
-// with or without attribute key and value specified,
+// with or without attribute key and value pairs specified,
 // and returns a struct with a pointer to it
 func (r Root) Find(args ...string) Root {
-	temp, ok := findOnce(r.Pointer, args, false, false)
+	if err := checkFindArgs(args); err != nil {
+		if debug {
+			panic(err.Error())
+		}
+		return Root{Error: err}
+	}
+	temp, ok := findOnce(r.Pointer, args, false)
 	if ok == false {
 		if debug {
 			panic("Element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
 		}
-		return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("element `%s` with attributes `%s` not found", args[0], strings.Join(args[1:], " ")))}
+		return Root{Error: newError(ErrElementNotFound, fmt.Sprintf("element `%s` with attributes `%s` not found%s", args[0], strings.Join(args[1:], " "), searchedFrom(r.Pointer)))}
 	}
 	return Root{Pointer: temp, NodeValue: temp.Data}
 }
 
 // FindAll finds all occurrences of the given tag name,
-// with or without key and value specified,
+// with or without key and value pairs specified,
 // and returns an array of structs, each having
-// the respective pointers
+// the respective pointers. It panics with an ErrInvalidArguments
+// Error when a key is missing its value, as Find returns it
 func (r Root) FindAll(args ...string) []Root {
-	temp := findAllofem(r.Pointer, args, false)
+	return r.FindAllWith(FindOptions{}, args...)
+}
+
+// FindOptions controls how far the FindAll functions search. The zero
+// value searches all descendants and returns every match
+type FindOptions struct {
+	// Limit stops the search once this many matches are found, if greater than 0
+	Limit int
+	// NonRecursive only searches the direct children, rather than all descendants
+	NonRecursive bool
+}
+
+// FindAllWith is like FindAll, but stops after opts.Limit matches and
+// only looks at direct children when opts.NonRecursive is set
+func (r Root) FindAllWith(opts FindOptions, args ...string) []Root {
+	mustFindArgs(args)
+	temp := findAllofem(r.Pointer, args, false, opts)
 	if len(temp) == 0 {
 		if debug {
 			panic("Element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
@@ -294,22 +363,36 @@ func (r Root) FindAll(args ...string) []Root {
 }
 
 // FindStrict finds the first occurrence of the given tag name
-// only if all the values of the provided attribute are an exact match
+// only if all the values of the provided attributes are an exact match
 func (r Root) FindStrict(args ...string) Root {
-	temp, ok := findOnce(r.Pointer, args, false, true)
+	if err := checkFindArgs(args); err != nil {
+		if debug {
+			panic(err.Error())
+		}
+		return Root{Error: err}
+	}
+	temp, ok := findOnce(r.Pointer, args, true)
 	if ok == false {
 		if debug {
 			panic("Element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
 		}
-		return Root{nil, "", newError(ErrElementNotFound, fmt.Sprintf("element `%s` with attributes `%s` not found", args[0], strings.Join(args[1:], " ")))}
+		return Root{nil, "", newError(ErrElementNotFound, fmt.Sprintf("element `%s` with attributes `%s` not found%s", args[0], strings.Join(args[1:], " "), searchedFrom(r.Pointer)))}
 	}
 	return Root{Pointer: temp, NodeValue: temp.Data}
 }
 
 // FindAllStrict finds all occurrences of the given tag name
-// only if all the values of the provided attribute are an exact match
+// only if all the values of the provided attributes are an exact match.
+// Like FindAll, it panics when a key is missing its value
 func (r Root) FindAllStrict(args ...string) []Root {
-	temp := findAllofem(r.Pointer, args, true)
+	return r.FindAllStrictWith(FindOptions{}, args...)
+}
+
+// FindAllStrictWith is like FindAllStrict, but stops after opts.Limit matches
+// and only looks at direct children when opts.NonRecursive is set
+func (r Root) FindAllStrictWith(opts FindOptions, args ...string) []Root {
+	mustFindArgs(args)
+	temp := findAllofem(r.Pointer, args, true, opts)
 	if len(temp) == 0 {
 		if debug {
 			panic("Element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
@@ -353,35 +436,278 @@ func (r Root) FindPrevSibling() Root {
 // FindNextElementSibling finds the next element sibling of the pointer in the DOM
 // returning a struct with a pointer to it
 func (r Root) FindNextElementSibling() Root {
-	nextSibling := r.Pointer.NextSibling
+	var nextSibling *html.Node
+	if r.Pointer != nil {
+		nextSibling = nextElementSibling(r.Pointer)
+	}
 	if nextSibling == nil {
 		if debug {
 			panic("No next element sibling found")
 		}
 		return Root{Error: newError(ErrNoNextElementSibling, "no next element sibling found")}
 	}
-	if nextSibling.Type == html.ElementNode {
-		return Root{Pointer: nextSibling, NodeValue: nextSibling.Data}
-	}
-	p := Root{Pointer: nextSibling, NodeValue: nextSibling.Data}
-	return p.FindNextElementSibling()
+	return Root{Pointer: nextSibling, NodeValue: nextSibling.Data}
 }
 
 // FindPrevElementSibling finds the previous element sibling of the pointer in the DOM
 // returning a struct with a pointer to it
 func (r Root) FindPrevElementSibling() Root {
-	prevSibling := r.Pointer.PrevSibling
+	var prevSibling *html.Node
+	if r.Pointer != nil {
+		prevSibling = prevElementSibling(r.Pointer)
+	}
 	if prevSibling == nil {
 		if debug {
 			panic("No previous element sibling found")
 		}
 		return Root{Error: newError(ErrNoPreviousElementSibling, "no previous element sibling found")}
 	}
-	if prevSibling.Type == html.ElementNode {
-		return Root{Pointer: prevSibling, NodeValue: prevSibling.Data}
+	return Root{Pointer: prevSibling, NodeValue: prevSibling.Data}
+}
+
+// NextElementSiblings returns all element siblings following the pointer in the DOM
+func (r Root) NextElementSiblings() []Root {
+	siblings := []Root{}
+	if r.Pointer == nil {
+		return siblings
+	}
+	for s := nextElementSibling(r.Pointer); s != nil; s = nextElementSibling(s) {
+		siblings = append(siblings, Root{Pointer: s, NodeValue: s.Data})
+	}
+	return siblings
+}
+
+// PrevElementSiblings returns all element siblings preceding the pointer in the DOM,
+// starting with the closest one
+func (r Root) PrevElementSiblings() []Root {
+	siblings := []Root{}
+	if r.Pointer == nil {
+		return siblings
+	}
+	for s := prevElementSibling(r.Pointer); s != nil; s = prevElementSibling(s) {
+		siblings = append(siblings, Root{Pointer: s, NodeValue: s.Data})
+	}
+	return siblings
+}
+
+// FindNextSiblings finds all element siblings following the pointer
+// with the given tag name, with or without attribute key and value
+// pairs specified, matched in the same way as Find
+func (r Root) FindNextSiblings(args ...string) []Root {
+	return filterArgs(r.NextElementSiblings(), args)
+}
+
+// FindPrevSiblings finds all element siblings preceding the pointer
+// with the given tag name, with or without attribute key and value
+// pairs specified, starting with the closest one
+func (r Root) FindPrevSiblings(args ...string) []Root {
+	return filterArgs(r.PrevElementSiblings(), args)
+}
+
+// filterArgs keeps the elements of roots matching the arguments to the Find functions
+func filterArgs(roots []Root, args []string) []Root {
+	mustFindArgs(args)
+	matched := []Root{}
+	for _, s := range roots {
+		if matchArgs(s.Pointer, args, false) {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) == 0 && debug {
+		panic("Sibling `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return matched
+}
+
+// Index returns the position of the pointer among the element children
+// of its parent, starting from 0, or -1 if it is not an element
+func (r Root) Index() int {
+	if r.Pointer == nil || r.Pointer.Type != html.ElementNode {
+		return -1
+	}
+	i := 0
+	for s := prevElementSibling(r.Pointer); s != nil; s = prevElementSibling(s) {
+		i++
+	}
+	return i
+}
+
+// Parent returns the element containing the pointer in the DOM
+func (r Root) Parent() Root {
+	if r.Pointer == nil || parentElement(r.Pointer) == nil {
+		if debug {
+			panic("No parent found")
+		}
+		return Root{Error: newError(ErrNoParent, "no parent found")}
+	}
+	parent := r.Pointer.Parent
+	return Root{Pointer: parent, NodeValue: parent.Data}
+}
+
+// Parents returns all elements containing the pointer in the DOM,
+// starting with its parent and ending with the outermost one
+func (r Root) Parents() []Root {
+	var parents []Root
+	if r.Pointer == nil {
+		return parents
+	}
+	for p := parentElement(r.Pointer); p != nil; p = parentElement(p) {
+		parents = append(parents, Root{Pointer: p, NodeValue: p.Data})
+	}
+	return parents
+}
+
+// FindParent finds the closest element containing the pointer
+// which has the given tag name, with or without attribute
+// key and value pairs specified, matched in the same way as Find
+func (r Root) FindParent(args ...string) Root {
+	if err := checkFindArgs(args); err != nil {
+		if debug {
+			panic(err.Error())
+		}
+		return Root{Error: err}
+	}
+	for _, p := range r.Parents() {
+		if matchArgs(p.Pointer, args, false) {
+			return p
+		}
+	}
+	if debug {
+		panic("Parent `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return Root{Error: newError(ErrNoParent, fmt.Sprintf("parent `%s` with attributes `%s` not found", args[0], strings.Join(args[1:], " ")))}
+}
+
+// FindParents finds all elements containing the pointer which have
+// the given tag name, with or without attribute key and value pairs
+// specified, starting with the closest one
+func (r Root) FindParents(args ...string) []Root {
+	mustFindArgs(args)
+	parents := []Root{}
+	for _, p := range r.Parents() {
+		if matchArgs(p.Pointer, args, false) {
+			parents = append(parents, p)
+		}
+	}
+	if len(parents) == 0 && debug {
+		panic("Parent `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return parents
+}
+
+// NextElement returns the element following the pointer in document order,
+// which is its first child element if it has one
+func (r Root) NextElement() Root {
+	if r.Pointer != nil {
+		for n := nextNode(r.Pointer); n != nil; n = nextNode(n) {
+			if n.Type == html.ElementNode {
+				return Root{Pointer: n, NodeValue: n.Data}
+			}
+		}
+	}
+	if debug {
+		panic("No next element found")
+	}
+	return Root{Error: newError(ErrNoNextElement, "no next element found")}
+}
+
+// PreviousElement returns the element preceding the pointer in document order,
+// which is its parent if it has no previous sibling
+func (r Root) PreviousElement() Root {
+	if r.Pointer != nil {
+		for n := prevNode(r.Pointer); n != nil; n = prevNode(n) {
+			if n.Type == html.ElementNode {
+				return Root{Pointer: n, NodeValue: n.Data}
+			}
+		}
+	}
+	if debug {
+		panic("No previous element found")
+	}
+	return Root{Error: newError(ErrNoPreviousElement, "no previous element found")}
+}
+
+// FindNext finds the first element after the pointer in document order,
+// whatever its depth, with the given tag name and with or without
+// attribute key and value pairs specified
+func (r Root) FindNext(args ...string) Root {
+	if err := checkFindArgs(args); err != nil {
+		if debug {
+			panic(err.Error())
+		}
+		return Root{Error: err}
+	}
+	if r.Pointer != nil {
+		for n := nextNode(r.Pointer); n != nil; n = nextNode(n) {
+			if matchArgs(n, args, false) {
+				return Root{Pointer: n, NodeValue: n.Data}
+			}
+		}
+	}
+	if debug {
+		panic("Next element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return Root{Error: newError(ErrNoNextElement, fmt.Sprintf("next element `%s` with attributes `%s` not found", args[0], strings.Join(args[1:], " ")))}
+}
+
+// FindAllNext finds all elements after the pointer in document order
+// with the given tag name and with or without attribute key and value pairs
+func (r Root) FindAllNext(args ...string) []Root {
+	mustFindArgs(args)
+	pointers := []Root{}
+	if r.Pointer != nil {
+		for n := nextNode(r.Pointer); n != nil; n = nextNode(n) {
+			if matchArgs(n, args, false) {
+				pointers = append(pointers, Root{Pointer: n, NodeValue: n.Data})
+			}
+		}
+	}
+	if len(pointers) == 0 && debug {
+		panic("Next element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
 	}
-	p := Root{Pointer: prevSibling, NodeValue: prevSibling.Data}
-	return p.FindPrevElementSibling()
+	return pointers
+}
+
+// FindPrevious finds the closest element before the pointer in document order,
+// whatever its depth, with the given tag name and with or without
+// attribute key and value pairs specified
+func (r Root) FindPrevious(args ...string) Root {
+	if err := checkFindArgs(args); err != nil {
+		if debug {
+			panic(err.Error())
+		}
+		return Root{Error: err}
+	}
+	if r.Pointer != nil {
+		for n := prevNode(r.Pointer); n != nil; n = prevNode(n) {
+			if matchArgs(n, args, false) {
+				return Root{Pointer: n, NodeValue: n.Data}
+			}
+		}
+	}
+	if debug {
+		panic("Previous element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return Root{Error: newError(ErrNoPreviousElement, fmt.Sprintf("previous element `%s` with attributes `%s` not found", args[0], strings.Join(args[1:], " ")))}
+}
+
+// FindAllPrevious finds all elements before the pointer in document order
+// with the given tag name and with or without attribute key and value pairs,
+// starting with the closest one
+func (r Root) FindAllPrevious(args ...string) []Root {
+	mustFindArgs(args)
+	pointers := []Root{}
+	if r.Pointer != nil {
+		for n := prevNode(r.Pointer); n != nil; n = prevNode(n) {
+			if matchArgs(n, args, false) {
+				pointers = append(pointers, Root{Pointer: n, NodeValue: n.Data})
+			}
+		}
+	}
+	if len(pointers) == 0 && debug {
+		panic("Previous element `" + args[0] + "` with attributes `" + strings.Join(args[1:], " ") + "` not found")
+	}
+	return pointers
 }
 
 // Children returns all direct children of this DOME element.
@@ -395,6 +721,21 @@ func (r Root) Children() []Root {
 	return children
 }
 
+// ElementChildren returns the direct children of this DOM element that are elements,
+// leaving out text and comment nodes
+func (r Root) ElementChildren() []Root {
+	children := []Root{}
+	if r.Pointer == nil {
+		return children
+	}
+	for child := r.Pointer.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			children = append(children, Root{Pointer: child, NodeValue: child.Data})
+		}
+	}
+	return children
+}
+
 // Attrs returns a map containing all attributes
 func (r Root) Attrs() map[string]string {
 	if r.Pointer.Type != html.ElementNode {
@@ -409,39 +750,34 @@ func (r Root) Attrs() map[string]string {
 	return getKeyValue(r.Pointer.Attr)
 }
 
-// Text returns the string inside a non-nested element
+// Text returns the first text node directly inside the element that
+// isn't only whitespace. OwnText returns all of them joined together
 func (r Root) Text() string {
-	k := r.Pointer.FirstChild
-checkNode:
-	if k != nil && k.Type != html.TextNode {
-		k = k.NextSibling
-		if k == nil {
-			if debug {
-				panic("No text node found")
-			}
-			return ""
-		}
-		goto checkNode
+	if r.Pointer == nil || r.Pointer.FirstChild == nil {
+		return ""
 	}
-	if k != nil {
-		r, _ := regexp.Compile(`^\s+$`)
-		if ok := r.MatchString(k.Data); ok {
-			k = k.NextSibling
-			if k == nil {
-				if debug {
-					panic("No text node found")
-				}
-				return ""
-			}
-			goto checkNode
+	for k := r.Pointer.FirstChild; k != nil; k = k.NextSibling {
+		if k.Type == html.TextNode && strings.TrimLeft(k.Data, asciiSpace) != "" {
+			return k.Data
 		}
-		return k.Data
+	}
+	if debug {
+		panic("No text node found")
 	}
 	return ""
 }
 
-// HTML returns the HTML code for the specific element
+// HTML returns the HTML code for the specific element. Elements parsed
+// by XMLParse are written out as XML, like XML does, since HTML has no
+// way to write some of them, such as an RSS <link> holding its URL
 func (r Root) HTML() string {
+	if r.Pointer != nil && inXMLDocument(r.Pointer) {
+		s, err := r.XML()
+		if err != nil {
+			return ""
+		}
+		return s
+	}
 	var buf bytes.Buffer
 	if err := html.Render(&buf, r.Pointer); err != nil {
 		return ""
@@ -474,76 +810,140 @@ func (r Root) FullText() string {
 	return buf.String()
 }
 
+// matchElementName reports whether n has the tag name name, which can
+// have a namespace prefix. In XML documents, a name without a prefix only
+// matches elements without one, so that "link" doesn't find <atom:link>
 func matchElementName(n *html.Node, name string) bool {
-	return name == "" || name == n.Data
+	if name == "" {
+		return true
+	}
+	if n.Namespace == "" {
+		return name == n.Data
+	}
+	return name == n.Namespace+":"+n.Data || name == n.Data && !inXMLDocument(n)
 }
 
-// Using depth first search to find the first occurrence and return
-func findOnce(n *html.Node, args []string, uni bool, strict bool) (*html.Node, bool) {
-	if n == nil {
-		return nil, false
+// checkFindArgs makes sure the arguments to the Find functions are
+// a tag name followed by attribute key and value pairs
+func checkFindArgs(args []string) error {
+	if len(args)%2 == 0 {
+		return newError(ErrInvalidArguments, fmt.Sprintf("expected a tag name followed by attribute key and value pairs, got %d arguments `%s`", len(args), strings.Join(args, " ")))
 	}
-	if uni == true {
-		if n.Type == html.ElementNode && matchElementName(n, args[0]) {
-			if len(args) > 1 && len(args) < 4 {
-				for i := 0; i < len(n.Attr); i++ {
-					attr := n.Attr[i]
-					searchAttrName := args[1]
-					searchAttrVal := args[2]
-					if (strict && attributeAndValueEquals(attr, searchAttrName, searchAttrVal)) ||
-						(!strict && attributeContainsValue(attr, searchAttrName, searchAttrVal)) {
-						return n, true
-					}
-				}
-			} else if len(args) == 1 {
-				return n, true
+	return nil
+}
+
+// mustFindArgs panics when args aren't a tag name followed by attribute
+// key and value pairs. The Find functions returning several elements have
+// no other way of reporting it, and an empty result would hide the mistake
+func mustFindArgs(args []string) {
+	if err := checkFindArgs(args); err != nil {
+		panic(err)
+	}
+}
+
+// matchArgs reports whether n is an element with the tag name in args[0]
+// and all of the attribute key and value pairs following it
+func matchArgs(n *html.Node, args []string, strict bool) bool {
+	if n.Type != html.ElementNode || !matchElementName(n, args[0]) {
+		return false
+	}
+	for i := 1; i+1 < len(args); i += 2 {
+		found := false
+		for _, attr := range n.Attr {
+			if (strict && attributeAndValueEquals(attr, args[i], args[i+1])) ||
+				(!strict && attributeContainsValue(attr, args[i], args[i+1])) {
+				found = true
+				break
 			}
 		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Using depth first search to find the first occurrence and return
+func findOnce(n *html.Node, args []string, strict bool) (*html.Node, bool) {
+	return matchOnce(n, func(c *html.Node) bool {
+		return matchArgs(c, args, strict)
+	})
+}
+
+// Using depth first search to find all occurrences and return
+func findAllofem(n *html.Node, args []string, strict bool, opts FindOptions) []*html.Node {
+	return matchAll(n, func(c *html.Node) bool {
+		return matchArgs(c, args, strict)
+	}, opts)
+}
+
+// matchOnce returns the first descendant element of n, in document order, for which match holds
+func matchOnce(n *html.Node, match func(*html.Node) bool) (*html.Node, bool) {
+	if n == nil {
+		return nil, false
 	}
-	uni = true
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		p, q := findOnce(c, args, true, strict)
-		if q != false {
-			return p, q
+		if c.Type == html.ElementNode && match(c) {
+			return c, true
+		}
+		if p, ok := matchOnce(c, match); ok {
+			return p, true
 		}
 	}
 	return nil, false
 }
 
-// Using depth first search to find all occurrences and return
-func findAllofem(n *html.Node, args []string, strict bool) []*html.Node {
+// matchAll returns every descendant element of n, in document order, for which match holds,
+// stopping early or skipping deeper descendants as opts asks
+func matchAll(n *html.Node, match func(*html.Node) bool, opts FindOptions) []*html.Node {
 	if n == nil {
 		return nil
 	}
 	var nodeLinks = make([]*html.Node, 0, 10)
-	var f func(*html.Node, []string, bool)
-	f = func(n *html.Node, args []string, uni bool) {
-		if uni == true {
-			if n.Type == html.ElementNode && matchElementName(n, args[0]) {
-				if len(args) > 1 && len(args) < 4 {
-					for i := 0; i < len(n.Attr); i++ {
-						attr := n.Attr[i]
-						searchAttrName := args[1]
-						searchAttrVal := args[2]
-						if (strict && attributeAndValueEquals(attr, searchAttrName, searchAttrVal)) ||
-							(!strict && attributeContainsValue(attr, searchAttrName, searchAttrVal)) {
-							nodeLinks = append(nodeLinks, n)
-						}
-					}
-				} else if len(args) == 1 {
-					nodeLinks = append(nodeLinks, n)
+	var f func(*html.Node) bool
+	f = func(n *html.Node) bool {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && match(c) {
+				nodeLinks = append(nodeLinks, c)
+				if opts.Limit > 0 && len(nodeLinks) >= opts.Limit {
+					return true
 				}
 			}
+			if !opts.NonRecursive && f(c) {
+				return true
+			}
 		}
-		uni = true
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			f(c, args, true)
-		}
+		return false
 	}
-	f(n, args, false)
+	f(n)
 	return nodeLinks
 }
 
+// nextNode returns the node following n in document order
+func nextNode(n *html.Node) *html.Node {
+	if n.FirstChild != nil {
+		return n.FirstChild
+	}
+	for ; n != nil; n = n.Parent {
+		if n.NextSibling != nil {
+			return n.NextSibling
+		}
+	}
+	return nil
+}
+
+// prevNode returns the node preceding n in document order
+func prevNode(n *html.Node) *html.Node {
+	if n.PrevSibling == nil {
+		return n.Parent
+	}
+	n = n.PrevSibling
+	for n.LastChild != nil {
+		n = n.LastChild
+	}
+	return n
+}
+
 // attributeAndValueEquals reports when the html.Attribute attr has the same attribute name and value as from
 // provided arguments
 func attributeAndValueEquals(attr html.Attribute, attribute, value string) bool {
@@ -140,6 +140,106 @@ func TestFindNextPrevElement(t *testing.T) {
 	assert.Equal(t, "div", strings.TrimSpace(actual))
 }
 
+func TestElementSiblings(t *testing.T) {
+	var ids []string
+	for _, div := range doc.Find("div", "id", "2").NextElementSiblings() {
+		ids = append(ids, div.NodeValue+div.Attrs()["id"])
+	}
+	// the parser closes the open <p> before <ul>, leaving an empty <p> after it
+	assert.Equal(t, []string{"p", "p", "ul", "p", "div3", "div5"}, ids)
+	prev := doc.Find("div", "id", "2").PrevElementSiblings()
+	assert.Equal(t, 2, len(prev))
+	assert.Equal(t, "table", prev[1].NodeValue)
+
+	assert.Equal(t, 2, len(doc.Find("div", "id", "2").FindNextSiblings("div")))
+	assert.Equal(t, "5", doc.Find("div", "id", "2").FindNextSiblings("div", "id", "5")[0].Attrs()["id"])
+	assert.Equal(t, 2, len(doc.Find("p").FindPrevSiblings("div")))
+	assert.Empty(t, doc.Find("p").FindPrevSiblings("ul"))
+	assert.Empty(t, doc.Find("missing").NextElementSiblings())
+}
+
+func TestElementChildrenAndIndex(t *testing.T) {
+	children := doc.Find("ul").ElementChildren()
+	assert.Equal(t, 2, len(children))
+	assert.Equal(t, 5, len(doc.Find("ul").Children()))
+	assert.Equal(t, 1, children[1].Index())
+	assert.Equal(t, 0, doc.Find("table").Index())
+	assert.Equal(t, 2, doc.Find("div", "id", "2").Index())
+	assert.Equal(t, -1, doc.Find("div", "id", "0").FindNextSibling().Index())
+	assert.Equal(t, -1, doc.Find("missing").Index())
+}
+
+func TestParents(t *testing.T) {
+	td := doc.Find("h1").Parent()
+	assert.Equal(t, "td", td.NodeValue)
+	assert.Equal(t, "tr", td.Parent().NodeValue)
+	var names []string
+	for _, p := range doc.Find("a").Parents() {
+		names = append(names, p.NodeValue)
+	}
+	assert.Equal(t, []string{"li", "ul", "body", "html"}, names)
+
+	noParent := doc.Parent()
+	assert.Equal(t, ErrNoParent, noParent.Error.(Error).Type)
+	assert.Empty(t, doc.Parents())
+	assert.Equal(t, ErrNoParent, doc.Find("missing").Parent().Error.(Error).Type)
+}
+
+func TestFindParents(t *testing.T) {
+	inner := multipleClasses.FindAllStrict("div", "class", "first second")[1]
+	assert.Equal(t, "Inner multiple classes", inner.Text())
+	assert.Equal(t, "body", inner.FindParent("body").NodeValue)
+	assert.Equal(t, "div", inner.FindParent("div").NodeValue)
+	assert.Equal(t, 3, len(inner.FindParents("")))
+
+	span := doc.Find("span")
+	assert.Equal(t, "5", span.FindParent("div", "id", "5").Attrs()["id"])
+	assert.Equal(t, 1, len(span.FindParents("div")))
+
+	missing := span.FindParent("table")
+	assert.Equal(t, ErrNoParent, missing.Error.(Error).Type)
+	assert.Equal(t, "parent `table` with attributes `` not found", missing.Error.Error())
+	assert.Empty(t, span.FindParents("div", "id", "4"))
+	assert.Equal(t, ErrInvalidArguments, span.FindParent("div", "id").Error.(Error).Type)
+}
+
+func TestNextPrevElement(t *testing.T) {
+	// NextElement() descends into children before moving on
+	assert.Equal(t, "tbody", doc.Find("table").NextElement().NodeValue)
+	assert.Equal(t, "img", doc.Find("img").Parent().FindNextElementSibling().PreviousElement().NodeValue)
+	assert.Equal(t, "img", doc.Find("h1").Parent().PreviousElement().NodeValue)
+	assert.Equal(t, "div", doc.Find("div", "id", "2").PreviousElement().NodeValue)
+	assert.Equal(t, "1", doc.Find("div", "id", "2").PreviousElement().Attrs()["id"])
+
+	last := doc.Find("span").NextElement()
+	assert.Equal(t, ErrNoNextElement, last.Error.(Error).Type)
+	first := doc.PreviousElement()
+	assert.Equal(t, ErrNoPreviousElement, first.Error.(Error).Type)
+}
+
+func TestFindNextPrevious(t *testing.T) {
+	img := doc.Find("img")
+	assert.Equal(t, "Sample \"Hello, World\" Application", img.FindNext("h1").Text())
+	assert.Equal(t, "0", img.FindNext("div").Attrs()["id"])
+	assert.Equal(t, 6, len(img.FindAllNext("div")))
+	assert.Equal(t, "hello", img.FindNext("a", "href", "hello").Attrs()["href"])
+
+	span := doc.Find("span")
+	// ancestors come before the pointer in document order
+	assert.Equal(t, "5", span.FindPrevious("div").Attrs()["id"])
+	assert.Equal(t, "4", span.FindAllPrevious("div")[1].Attrs()["id"])
+	assert.Equal(t, []string{"hello", "hello.jsp"}, []string{
+		span.FindAllPrevious("a")[0].Attrs()["href"],
+		span.FindAllPrevious("a")[1].Attrs()["href"],
+	})
+	assert.Equal(t, "title", span.FindPrevious("title").NodeValue)
+
+	assert.Equal(t, ErrNoNextElement, span.FindNext("div").Error.(Error).Type)
+	assert.Equal(t, ErrNoPreviousElement, img.FindPrevious("p").Error.(Error).Type)
+	assert.Empty(t, img.FindAllPrevious("p"))
+	assert.Empty(t, span.FindAllNext("div"))
+}
+
 func TestFindAll(t *testing.T) {
 	// FindAll() and Attrs()
 	allDivs := doc.FindAll("div")
@@ -150,6 +250,23 @@ func TestFindAll(t *testing.T) {
 	}
 }
 
+func TestFindAllWithOptions(t *testing.T) {
+	// Limit
+	actual := doc.FindAllWith(FindOptions{Limit: 2}, "div")
+	assert.Equal(t, 2, len(actual))
+	assert.Equal(t, "1", actual[1].Attrs()["id"])
+	// NonRecursive
+	body := multipleClasses.Find("body")
+	actual = body.FindAllWith(FindOptions{NonRecursive: true}, "div", "class", "first")
+	assert.Equal(t, 3, len(actual))
+	actual = body.FindAllStrictWith(FindOptions{NonRecursive: true, Limit: 1}, "div", "class", "first second")
+	assert.Equal(t, 1, len(actual))
+	assert.Equal(t, "Multiple classes", actual[0].Text())
+	actual = body.FindAllByWith(FindOptions{NonRecursive: true}, Tag(Exact("div")), Attr("class", Word("third")))
+	assert.Equal(t, 1, len(actual))
+	assert.Empty(t, doc.FindAllWith(FindOptions{NonRecursive: true}, "div"))
+}
+
 func TestFindAllBySingleClass(t *testing.T) {
 	actual := multipleClasses.FindAll("div", "class", "first")
 	assert.Equal(t, 6, len(actual))
@@ -210,12 +327,61 @@ func TestNewErrorReturnsInspectableError(t *testing.T) {
 }
 
 func TestFindReturnsInspectableError(t *testing.T) {
-	r := doc.Find("bogus", "thing")
+	r := doc.Find("bogus", "thing", "value")
 	assert.IsType(t, Error{}, r.Error)
-	assert.Equal(t, "element `bogus` with attributes `thing` not found", r.Error.Error())
+	assert.Equal(t, "element `bogus` with attributes `thing value` not found", r.Error.Error())
 	assert.Equal(t, ErrElementNotFound, r.Error.(Error).Type)
 }
 
+const formHTML = `
+<form>
+  <input type="hidden" name="session" value="1">
+  <input type="hidden" name="csrf" value="2">
+  <input type="text" name="csrf" value="3">
+</form>
+`
+
+func TestFindMultipleAttributes(t *testing.T) {
+	form := HTMLParse(formHTML)
+	actual := form.Find("input", "type", "hidden", "name", "csrf").Attrs()["value"]
+	assert.Equal(t, "2", actual)
+	actual = form.FindStrict("input", "name", "csrf", "type", "text").Attrs()["value"]
+	assert.Equal(t, "3", actual)
+	assert.Equal(t, 1, len(form.FindAll("input", "type", "hidden", "name", "session")))
+	assert.Equal(t, 2, len(form.FindAllStrict("input", "name", "csrf")))
+	assert.Equal(t, 0, len(form.FindAll("input", "type", "text", "name", "session")))
+}
+
+func TestFindOddArgumentsReturnsError(t *testing.T) {
+	r := doc.Find("bogus", "thing")
+	assert.IsType(t, Error{}, r.Error)
+	assert.Equal(t, ErrInvalidArguments, r.Error.(Error).Type)
+	r = doc.FindStrict("input", "type", "hidden", "name")
+	assert.Equal(t, ErrInvalidArguments, r.Error.(Error).Type)
+	r = doc.Find()
+	assert.Equal(t, ErrInvalidArguments, r.Error.(Error).Type)
+
+	// functions returning several elements panic instead
+	msg := "expected a tag name followed by attribute key and value pairs, got 2 arguments `div id`"
+	for _, f := range []func(){
+		func() { doc.FindAll("div", "id") },
+		func() { doc.FindAllStrict("div", "id") },
+		func() { doc.FindAllWith(FindOptions{Limit: 1}, "div", "id") },
+		func() { doc.FindAllStrictWith(FindOptions{}, "div", "id") },
+		func() { doc.Find("div").FindParents("div", "id") },
+		func() { doc.Find("div").FindNextSiblings("div", "id") },
+		func() { doc.Find("div").FindPrevSiblings("div", "id") },
+		func() { doc.Find("div").FindAllNext("div", "id") },
+		func() { doc.Find("div").FindAllPrevious("div", "id") },
+	} {
+		assert.PanicsWithError(t, msg, f)
+	}
+	defer func() {
+		assert.Equal(t, ErrInvalidArguments, recover().(Error).Type)
+	}()
+	doc.FindAll()
+}
+
 // Similar test: https://github.com/hashicorp/go-retryablehttp/blob/master/client_test.go#L616
 func TestClient_Post(t *testing.T) {
 	// Mock server which always responds 200.
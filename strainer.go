@@ -0,0 +1,196 @@
+package soup
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// closedBy holds, for elements whose end tag can be left out, the start
+// tags which close them. <p> is closed by any of the blockElements
+var closedBy = map[string]map[string]bool{
+	"li":       {"li": true},
+	"dt":       {"dt": true, "dd": true},
+	"dd":       {"dt": true, "dd": true},
+	"option":   {"option": true, "optgroup": true},
+	"optgroup": {"optgroup": true},
+	"rt":       {"rt": true, "rp": true},
+	"rp":       {"rt": true, "rp": true},
+	"tr":       {"tr": true, "thead": true, "tbody": true, "tfoot": true},
+	"td":       {"td": true, "th": true, "tr": true, "thead": true, "tbody": true, "tfoot": true},
+	"th":       {"td": true, "th": true, "tr": true, "thead": true, "tbody": true, "tfoot": true},
+	"thead":    {"tbody": true, "tfoot": true},
+	"tbody":    {"tbody": true, "tfoot": true},
+	"tfoot":    {"tbody": true},
+}
+
+// closedByEnd holds, for elements whose end tag can be left out, the end
+// tags of the elements they are in which close them. <p> is closed by the
+// end tag of any of the blockElements, and every element by </body> and </html>
+var closedByEnd = map[string]map[string]bool{
+	"li":       {"ul": true, "ol": true, "menu": true},
+	"dt":       {"dl": true},
+	"dd":       {"dl": true},
+	"option":   {"select": true, "datalist": true, "optgroup": true},
+	"optgroup": {"select": true},
+	"rt":       {"ruby": true},
+	"rp":       {"ruby": true},
+	"tr":       {"table": true, "thead": true, "tbody": true, "tfoot": true},
+	"td":       {"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true},
+	"th":       {"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true},
+	"thead":    {"table": true},
+	"tbody":    {"table": true},
+	"tfoot":    {"table": true},
+}
+
+// fragmentContexts holds the element which elements that can only appear
+// inside some other element are parsed in, instead of <body>
+var fragmentContexts = map[string]string{
+	"caption": "table", "colgroup": "table", "thead": "table", "tbody": "table", "tfoot": "table",
+	"tr": "tbody", "td": "tr", "th": "tr", "col": "colgroup",
+	"option": "select", "optgroup": "select",
+}
+
+// strainer cuts the source of the subtrees whose root matches m out of
+// a stream of tokens, without building the rest of the tree
+type strainer struct {
+	m   Matcher
+	buf bytes.Buffer
+	// open holds the names of the elements open in the current subtree,
+	// starting with its root, and is empty outside of one
+	open []string
+}
+
+// closes reports whether the start tag name ends the current subtree
+// because it implies the end tag of its root. Elements in between which
+// are blocks of their own, such as a nested <ul> in an <li>, keep it open
+func (s *strainer) closes(name string) bool {
+	root := s.open[0]
+	if root == "p" {
+		return blockElements[name]
+	}
+	if !closedBy[root][name] {
+		return false
+	}
+	for _, e := range s.open[1:] {
+		if blockElements[e] && e != "div" && e != "p" {
+			return false
+		}
+	}
+	return true
+}
+
+// ends reports whether the end tag name, which matches no element open in
+// the current subtree, closes it. Other stray end tags, such as a </span>
+// without a <span>, are left to the fragment parser
+func (s *strainer) ends(name string) bool {
+	root := s.open[0]
+	switch {
+	case name == "body" || name == "html":
+		return root == "p" || closedBy[root] != nil
+	case root == "p":
+		return blockElements[name] && name != "p" || name == "td" || name == "th"
+	}
+	return closedByEnd[root][name]
+}
+
+// parseOnly parses the subtrees of the HTML read from r whose root matches
+// m into the <body> of an otherwise empty document. Only the subtree being
+// read and those kept are held in memory
+func parseOnly(r io.Reader, m Matcher) (*html.Node, error) {
+	doc, err := html.Parse(strings.NewReader(""))
+	if err != nil {
+		return nil, err
+	}
+	body := doc.FirstChild.LastChild
+	s := &strainer{m: m}
+	flush := func() error {
+		if len(s.open) == 0 {
+			return nil
+		}
+		ctx := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+		if name, ok := fragmentContexts[s.open[0]]; ok {
+			ctx = &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name))}
+		}
+		nodes, err := html.ParseFragment(&s.buf, ctx)
+		if err != nil {
+			return err
+		}
+		for _, n := range nodes {
+			body.AppendChild(n)
+		}
+		s.buf.Reset()
+		s.open = s.open[:0]
+		return nil
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return nil, err
+			}
+			return doc, flush()
+		}
+		raw := z.Raw()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if len(s.open) > 0 && s.closes(tok.Data) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			if len(s.open) == 0 {
+				n := &html.Node{Type: html.ElementNode, Data: tok.Data, DataAtom: tok.DataAtom, Attr: tok.Attr}
+				if !s.m.Match(Root{Pointer: n, NodeValue: n.Data}) {
+					continue
+				}
+			}
+			s.buf.Write(raw)
+			if !voidElements[tok.Data] {
+				s.open = append(s.open, tok.Data)
+			} else if len(s.open) == 0 {
+				// a void element is a subtree of its own
+				s.open = append(s.open, tok.Data)
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		case html.EndTagToken:
+			if len(s.open) == 0 {
+				continue
+			}
+			name, _ := z.TagName()
+			k := len(s.open) - 1
+			for k >= 0 && s.open[k] != string(name) {
+				k--
+			}
+			switch {
+			case k > 0:
+				s.buf.Write(raw)
+				s.open = s.open[:k]
+			case k == 0:
+				s.buf.Write(raw)
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			case s.ends(string(name)):
+				// the end tag of an element the subtree is in
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			default:
+				s.buf.Write(raw)
+			}
+		default:
+			if len(s.open) > 0 {
+				s.buf.Write(raw)
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package soup
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const strainerPage = `<!DOCTYPE html>
+<html><head><title>Export</title></head>
+<body>
+<div class="nav"><a href="/">Home</a> <a href="/about">About</a></div>
+<p>Some text with a <a href="/inline">link &amp; more</a>.</p>
+<table id="prices"><tr><th>Item</th><th>Price</th></tr><tr><td><a href="/tea">Tea</a></td><td>3</td></tr></table>
+<ul><li>one<li class="pick">two<ul><li>nested</ul><li>three</ul>
+<select><option>a<option class="pick">b<option>c</select>
+<img src="x.png" class="pick">
+</body></html>`
+
+func strained(s string, m Matcher) Root {
+	return HTMLParseReader(strings.NewReader(s), ParseOptions{ParseOnly: m})
+}
+
+func TestParseOnly(t *testing.T) {
+	page := strained(strainerPage, Tag(OneOf("a", "table")))
+	assert.NoError(t, page.Error)
+	assert.Equal(t, "html", page.NodeValue)
+	assert.Equal(t, `<html><head></head><body><a href="/">Home</a><a href="/about">About</a><a href="/inline">link &amp; more</a>`+
+		`<table id="prices"><tbody><tr><th>Item</th><th>Price</th></tr><tr><td><a href="/tea">Tea</a></td><td>3</td></tr></tbody></table></body></html>`, page.HTML())
+
+	// matches inside kept subtrees are found as well
+	var hrefs []string
+	for _, a := range page.FindAll("a") {
+		href, _ := a.Attr("href")
+		hrefs = append(hrefs, href)
+	}
+	assert.Equal(t, []string{"/", "/about", "/inline", "/tea"}, hrefs)
+	assert.Len(t, page.Find("table").FindAll("tr"), 2)
+	assert.Equal(t, ErrElementNotFound, page.Find("title").Error.(Error).Type)
+	assert.Equal(t, "utf-8", page.Encoding())
+}
+
+func TestParseOnlyAttributes(t *testing.T) {
+	page := strained(strainerPage, Attr("class", Word("pick")))
+	children := page.Document().Body().ElementChildren()
+	var got []string
+	for _, c := range children {
+		html, _ := c.InnerHTML()
+		got = append(got, c.NodeValue+":"+html)
+	}
+	assert.Equal(t, []string{"li:two<ul><li>nested</li></ul>", "option:b", "img:"}, got)
+
+	rows := strained(strainerPage, Tag(Exact("tr")))
+	assert.Len(t, rows.FindAll("tr"), 2)
+	assert.Equal(t, "3", rows.FindAll("td")[1].Text())
+
+	cells := strained(`<table><tr><td>1<td>2<b>bold<td>3</table>`, Tag(Exact("td")))
+	assert.Len(t, cells.FindAll("td"), 3)
+	assert.Equal(t, "bold", cells.Find("b").Text())
+
+	paras := strained(`<p>one<p>two<div>three</div><p>four</div>`, Tag(Exact("p")))
+	assert.Len(t, paras.FindAll("p"), 3)
+	assert.Equal(t, ErrElementNotFound, paras.Find("div").Error.(Error).Type)
+
+	// script contents aren't mistaken for tags
+	scripts := strained(`<script>if (a < b) { document.write("<a href=x>") }</script><a href="y">y</a>`, Tag(Exact("a")))
+	assert.Len(t, scripts.FindAll("a"), 1)
+
+	none := strained(strainerPage, Tag(Exact("video")))
+	assert.NoError(t, none.Error)
+	assert.Equal(t, "<html><head></head><body></body></html>", none.HTML())
+}
+
+func TestParseOnlyStrayEndTags(t *testing.T) {
+	// stray end tags inside a kept subtree are dropped, as the full parser does
+	for _, src := range []string{
+		`<p>x <a href=1>o</span>ne</a></p>`,
+		`<p>para one <span>s</span></b> after stray</p>`,
+		`<ul><li>one</div> more<li>two</ul>`,
+		`<table><tr><td>a</span>b<td>c</p>d</table>`,
+	} {
+		root := HTMLParse(src).Find("body").Pointer.FirstChild
+		want := Root{Pointer: root}.HTML()
+		page := strained(src, Tag(Exact(root.Data)))
+		assert.Equal(t, want, Root{Pointer: page.Find("body").Pointer.FirstChild}.HTML(), src)
+	}
+
+	// the end tag of an element the subtree is in still closes it
+	page := strained(`<div><p>one</div><p>two</p><ul><li>a</ul>b<ul><li>c</li></ul>`, Tag(OneOf("p", "li")))
+	assert.Equal(t, []string{"one", "two", "a", "c"}, selectedText(page.FindAllBy(Tag(OneOf("p", "li")))))
+}
+
+// exportReader produces a large HTML export without keeping it in memory
+type exportReader struct {
+	rows, row int
+	buf       strings.Reader
+}
+
+func (e *exportReader) Read(p []byte) (int, error) {
+	for e.buf.Len() == 0 {
+		switch {
+		case e.row > e.rows:
+			return 0, io.EOF
+		case e.row == e.rows:
+			e.buf.Reset(`</div><a href="/last">last</a></body></html>`)
+		case e.row == 0:
+			e.buf.Reset(`<html><body><div>`)
+		default:
+			e.buf.Reset(fmt.Sprintf(`<p class="row">Row %d with <b>some</b> text and a <i>few</i> tags to skip</p>`, e.row))
+		}
+		e.row++
+	}
+	return e.buf.Read(p)
+}
+
+func TestParseOnlyLargeDocument(t *testing.T) {
+	page := HTMLParseReader(&exportReader{rows: 100000}, ParseOptions{ParseOnly: Tag(Exact("a")), Encoding: "utf-8"})
+	assert.NoError(t, page.Error)
+	links := page.FindAll("a")
+	assert.Len(t, links, 1)
+	assert.Equal(t, "last", links[0].Text())
+}
@@ -0,0 +1,222 @@
+package soup
+
+import (
+	"iter"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TextOptions controls how GetText puts together the text below an element
+type TextOptions struct {
+	// Separator is put between consecutive strings
+	Separator string
+	// Strip removes leading and trailing whitespace from every string
+	// and leaves out the strings which are only whitespace
+	Strip bool
+	// SkipScripts leaves out the contents of script, style, noscript and template elements
+	SkipScripts bool
+	// SkipHidden leaves out elements which aren't rendered, those with the
+	// hidden attribute, aria-hidden="true" or display:none in their style
+	SkipHidden bool
+	// Blocks lays out the text the way a browser would, putting block
+	// elements such as <p>, <div> and <li> and table rows on their own
+	// lines, separating table cells with tabs and collapsing whitespace
+	Blocks bool
+}
+
+// scriptElements hold text which isn't displayed as part of the page
+var scriptElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+}
+
+// blockElements start on a new line when rendered
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "caption": true,
+	"dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "section": true, "summary": true, "table": true,
+	"tbody": true, "tfoot": true, "thead": true, "tr": true, "ul": true,
+}
+
+// asciiSpace holds the characters HTML treats as whitespace. Unlike
+// unicode.IsSpace, it leaves out non-breaking spaces
+const asciiSpace = " \t\n\f\r"
+
+// spaceReplacer turns all HTML whitespace into spaces
+var spaceReplacer = strings.NewReplacer("\t", " ", "\n", " ", "\f", " ", "\r", " ")
+
+// normalizeSpace collapses runs of whitespace into a single space, as
+// browsers do when rendering text, then turns non-breaking spaces, which
+// browsers keep, into regular ones, and trims spaces from both ends
+func normalizeSpace(s string) string {
+	fields := strings.FieldsFunc(s, func(c rune) bool {
+		return strings.ContainsRune(asciiSpace, c)
+	})
+	return strings.Trim(strings.ReplaceAll(strings.Join(fields, " "), "\u00a0", " "), " ")
+}
+
+// isHidden reports whether the element n isn't rendered by browsers
+func isHidden(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if strings.EqualFold(strings.TrimSpace(attr.Val), "true") {
+				return true
+			}
+		case "style":
+			style := strings.ToLower(strings.Join(strings.Fields(attr.Val), ""))
+			for _, decl := range strings.Split(style, ";") {
+				if decl == "display:none" || strings.HasPrefix(decl, "display:none!") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// breaks between strings, from weakest to strongest
+const (
+	noBreak = iota
+	cellBreak
+	lineBreak
+)
+
+// textWriter puts strings together, remembering the strongest break
+// asked for since the last one it wrote
+type textWriter struct {
+	opts    TextOptions
+	buf     strings.Builder
+	pending int
+}
+
+func (w *textWriter) text(s string) {
+	if w.opts.Strip {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return
+		}
+	}
+	if w.opts.Blocks {
+		// keep line and cell breaks apart from the whitespace in the text
+		s = spaceReplacer.Replace(s)
+	}
+	if w.buf.Len() > 0 {
+		switch w.pending {
+		case lineBreak:
+			w.buf.WriteByte('\n')
+		case cellBreak:
+			w.buf.WriteByte('\t')
+		default:
+			w.buf.WriteString(w.opts.Separator)
+		}
+	}
+	w.pending = noBreak
+	w.buf.WriteString(s)
+}
+
+func (w *textWriter) brk(kind int) {
+	w.pending = max(w.pending, kind)
+}
+
+func (w *textWriter) walk(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			w.text(c.Data)
+		case html.ElementNode:
+			if w.opts.SkipScripts && scriptElements[c.Data] || w.opts.SkipHidden && isHidden(c) {
+				continue
+			}
+			switch {
+			case !w.opts.Blocks:
+				w.walk(c)
+			case c.Data == "br":
+				w.brk(lineBreak)
+			case c.Data == "td" || c.Data == "th":
+				w.brk(cellBreak)
+				w.walk(c)
+				w.brk(cellBreak)
+			case blockElements[c.Data]:
+				w.brk(lineBreak)
+				w.walk(c)
+				w.brk(lineBreak)
+			default:
+				w.walk(c)
+			}
+		}
+	}
+}
+
+// String returns the text written so far, laid out in lines in Blocks mode
+func (w *textWriter) String() string {
+	if !w.opts.Blocks {
+		return w.buf.String()
+	}
+	var lines []string
+	for _, line := range strings.Split(w.buf.String(), "\n") {
+		cells := strings.Split(line, "\t")
+		for i := range cells {
+			cells[i] = normalizeSpace(cells[i])
+		}
+		if line = strings.Trim(strings.Join(cells, "\t"), "\t"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// GetText returns the text below the pointer put together as described
+// by opts. With the zero TextOptions it returns the same as FullText
+func (r Root) GetText(opts TextOptions) string {
+	w := &textWriter{opts: opts}
+	if r.Pointer != nil {
+		if r.Pointer.Type == html.TextNode {
+			w.text(r.Pointer.Data)
+		} else {
+			w.walk(r.Pointer)
+		}
+	}
+	return w.String()
+}
+
+// StrippedStrings returns an iterator over the text below the pointer,
+// with leading and trailing whitespace removed from each string and the
+// strings which are only whitespace left out
+func (r Root) StrippedStrings() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for s := range r.Strings() {
+			if t := strings.TrimSpace(s.Pointer.Data); t != "" && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// OwnText returns the text directly inside the element, leaving out the
+// text inside its child elements
+func (r Root) OwnText() string {
+	if r.Pointer == nil {
+		return ""
+	}
+	return ownText(r.Pointer)
+}
+
+// NormalizedText returns the full text below the pointer with whitespace
+// collapsed and non-breaking spaces turned into regular ones, the way
+// the text is displayed by a browser
+func (r Root) NormalizedText() string {
+	return normalizeSpace(r.GetText(TextOptions{}))
+}
+
+// InnerText returns the text below the pointer like the innerText of
+// an element in a browser: laid out in lines, with whitespace collapsed,
+// and leaving out scripts and hidden elements
+func (r Root) InnerText() string {
+	return r.GetText(TextOptions{Blocks: true, SkipScripts: true, SkipHidden: true})
+}
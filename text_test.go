@@ -0,0 +1,85 @@
+package soup
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const layoutHTML = `<html><head><style>p { color: red }</style></head><body>
+<h1>Report</h1>
+<p>First  paragraph<br>second line</p>
+<ul><li>One</li><li>Two <b>bold</b></li></ul>
+<table><tr><th>Name</th><th>Age</th></tr><tr><td>Ann</td><td>31</td></tr></table>
+<script>var x = 1;</script>
+<div>Last <span>words</span></div>
+</body></html>`
+
+func TestGetText(t *testing.T) {
+	page := HTMLParse(layoutHTML)
+	body := page.Find("body")
+	assert.Equal(t, body.FullText(), body.GetText(TextOptions{}))
+
+	table := page.Find("table")
+	assert.Equal(t, "NameAgeAnn31", table.GetText(TextOptions{}))
+	assert.Equal(t, "Name Age Ann 31", table.GetText(TextOptions{Separator: " "}))
+	assert.Equal(t, "Report|First  paragraph|second line|One|Two|bold|Name|Age|Ann|31|Last|words",
+		body.GetText(TextOptions{Separator: "|", Strip: true, SkipScripts: true}))
+	assert.Contains(t, page.GetText(TextOptions{Strip: true}), "var x = 1;")
+	assert.NotContains(t, page.GetText(TextOptions{SkipScripts: true}), "color")
+	assert.Equal(t, "second line", page.Find("br").FindNextSibling().GetText(TextOptions{}))
+	assert.Equal(t, "", Root{}.GetText(TextOptions{}))
+}
+
+func TestGetTextBlocks(t *testing.T) {
+	body := HTMLParse(layoutHTML).Find("body")
+	assert.Equal(t, "Report\nFirst paragraph\nsecond line\nOne\nTwo bold\nName\tAge\nAnn\t31\nLast words",
+		body.GetText(TextOptions{Blocks: true, SkipScripts: true}))
+	assert.Equal(t, "One\nTwo - bold", body.Find("ul").GetText(TextOptions{Blocks: true, Separator: " - ", Strip: true}))
+}
+
+func TestStrippedStrings(t *testing.T) {
+	ul := HTMLParse(layoutHTML).Find("ul")
+	assert.Equal(t, []string{"One", "Two", "bold"}, slices.Collect(ul.StrippedStrings()))
+	for s := range HTMLParse(layoutHTML).StrippedStrings() {
+		assert.Equal(t, "p { color: red }", s)
+		break
+	}
+}
+
+func TestOwnAndNormalizedText(t *testing.T) {
+	p := HTMLParse("<p>\n  One <b>two</b>\tthree&nbsp;&nbsp;four\n</p>").Find("p")
+	assert.Equal(t, "\n  One \tthree  four\n", p.OwnText())
+	assert.Equal(t, "One two three  four", p.NormalizedText())
+	assert.Equal(t, "\n  One ", p.Text())
+	assert.Equal(t, "", Root{}.OwnText())
+	assert.Equal(t, "", Root{}.Text())
+
+	// non-breaking spaces at either end are trimmed like other whitespace
+	p = HTMLParse("<p>&nbsp;p&nbsp;&nbsp;q&nbsp;</p>").Find("p")
+	assert.Equal(t, "p  q", p.NormalizedText())
+	assert.Equal(t, "p  q", p.InnerText())
+	assert.Equal(t, "a\tb", HTMLParse("<table><tr><td>&nbsp;a</td><td>b&nbsp;</td></tr></table>").Find("tr").InnerText())
+}
+
+func TestTextWithoutChildren(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+	page := HTMLParse("<p></p><div><b>bold</b></div>")
+	assert.Equal(t, "", page.Find("p").Text())
+	assert.Panics(t, func() { page.Find("div").Text() })
+}
+
+func TestInnerText(t *testing.T) {
+	page := HTMLParse(`<div>
+	<p>Shown	text</p>
+	<p hidden>Hidden</p>
+	<span aria-hidden="true">Icon</span>
+	<span aria-hidden="false">Visible</span>
+	<div style="color: red; DISPLAY: none !important">Collapsed</div>
+	<script>ignored()</script>
+	<ul><li>A</li><li>B</li></ul>
+</div>`)
+	assert.Equal(t, "Shown text\nVisible\nA\nB", page.Find("div").InnerText())
+}
@@ -0,0 +1,232 @@
+package soup
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// NewTag creates an element with the given tag name and attributes,
+// which are sorted by key. The element is not part of any DOM until
+// it is inserted with one of Append, Prepend, InsertBefore or InsertAfter
+func NewTag(name string, attrs map[string]string) Root {
+	n := &html.Node{Type: html.ElementNode, Data: name, DataAtom: atom.Lookup([]byte(name))}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		n.Attr = append(n.Attr, html.Attribute{Key: k, Val: attrs[k]})
+	}
+	return Root{Pointer: n, NodeValue: n.Data}
+}
+
+// NewText creates a text node holding s, which is escaped when rendered
+func NewText(s string) Root {
+	return Root{Pointer: &html.Node{Type: html.TextNode, Data: s}, NodeValue: s}
+}
+
+// NewComment creates a comment node holding s
+func NewComment(s string) Root {
+	return Root{Pointer: &html.Node{Type: html.CommentNode, Data: s}, NodeValue: s}
+}
+
+// ParseFragment parses a piece of HTML as it would be parsed inside the
+// context element, which defaults to <body> when context has no pointer.
+// The returned nodes are not part of any DOM until they are inserted
+func ParseFragment(s string, context Root) ([]Root, error) {
+	ctx := context.Pointer
+	if ctx == nil {
+		ctx = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	} else if ctx.Type != html.ElementNode {
+		if debug {
+			panic("Fragment context is not an ElementNode")
+		}
+		return nil, newError(ErrUnableToParse, "fragment context is not an element")
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), ctx)
+	if err != nil {
+		if debug {
+			panic("Unable to parse the HTML fragment")
+		}
+		return nil, newError(ErrUnableToParse, "unable to parse the HTML fragment")
+	}
+	roots := make([]Root, 0, len(nodes))
+	for _, n := range nodes {
+		roots = append(roots, Root{Pointer: n, NodeValue: n.Data})
+	}
+	return roots, nil
+}
+
+// Decompose removes the pointer and everything below it from the DOM
+func (r Root) Decompose() {
+	if r.Pointer != nil {
+		detach(r.Pointer)
+	}
+}
+
+// Extract removes the pointer from the DOM and returns it, so that it
+// can be inserted elsewhere or used as a tree of its own
+func (r Root) Extract() Root {
+	if r.Pointer == nil {
+		return Root{Error: modificationError("nothing to extract")}
+	}
+	detach(r.Pointer)
+	return Root{Pointer: r.Pointer, NodeValue: r.Pointer.Data}
+}
+
+// ReplaceWith puts other in the place of the pointer, removing the pointer
+// from the DOM. If other is already in a tree it is moved rather than copied
+func (r Root) ReplaceWith(other Root) error {
+	if r.Pointer == nil || r.Pointer.Parent == nil {
+		return modificationError("cannot replace a node without a parent")
+	}
+	if other.Pointer == r.Pointer {
+		return nil
+	}
+	if err := r.InsertBefore(other); err != nil {
+		return err
+	}
+	detach(r.Pointer)
+	return nil
+}
+
+// Wrap puts the pointer inside wrapper, which takes its place in the DOM,
+// and returns wrapper. The pointer becomes the last child of wrapper
+func (r Root) Wrap(wrapper Root) Root {
+	if r.Pointer == nil {
+		return Root{Error: modificationError("nothing to wrap")}
+	}
+	if err := checkInsert(r.Pointer, wrapper.Pointer); err != nil {
+		return Root{Error: err}
+	}
+	if r.Pointer.Parent != nil {
+		if err := r.InsertBefore(wrapper); err != nil {
+			return Root{Error: err}
+		}
+	}
+	if err := wrapper.Append(r); err != nil {
+		return Root{Error: err}
+	}
+	return Root{Pointer: wrapper.Pointer, NodeValue: wrapper.Pointer.Data}
+}
+
+// Unwrap replaces the pointer with its children, removing only the
+// element itself from the DOM
+func (r Root) Unwrap() error {
+	if r.Pointer == nil || r.Pointer.Parent == nil {
+		return modificationError("cannot unwrap a node without a parent")
+	}
+	parent := r.Pointer.Parent
+	for c := r.Pointer.FirstChild; c != nil; c = r.Pointer.FirstChild {
+		r.Pointer.RemoveChild(c)
+		parent.InsertBefore(c, r.Pointer)
+	}
+	detach(r.Pointer)
+	return nil
+}
+
+// Clear removes all children of the pointer from the DOM
+func (r Root) Clear() {
+	if r.Pointer == nil {
+		return
+	}
+	for c := r.Pointer.FirstChild; c != nil; c = r.Pointer.FirstChild {
+		r.Pointer.RemoveChild(c)
+	}
+}
+
+// InsertBefore inserts nodes into the DOM right before the pointer, as its
+// previous siblings. Nodes which are already in a tree are moved
+func (r Root) InsertBefore(nodes ...Root) error {
+	if r.Pointer == nil || r.Pointer.Parent == nil {
+		return modificationError("cannot insert next to a node without a parent")
+	}
+	return insertNodes(r.Pointer.Parent, r.Pointer, nodes)
+}
+
+// InsertAfter inserts nodes into the DOM right after the pointer, as its
+// next siblings. Nodes which are already in a tree are moved
+func (r Root) InsertAfter(nodes ...Root) error {
+	if r.Pointer == nil || r.Pointer.Parent == nil {
+		return modificationError("cannot insert next to a node without a parent")
+	}
+	return insertNodes(r.Pointer.Parent, r.Pointer.NextSibling, nodes)
+}
+
+// Append adds nodes as the last children of the pointer.
+// Nodes which are already in a tree are moved
+func (r Root) Append(nodes ...Root) error {
+	if r.Pointer == nil {
+		return modificationError("nothing to append to")
+	}
+	return insertNodes(r.Pointer, nil, nodes)
+}
+
+// Prepend adds nodes as the first children of the pointer.
+// Nodes which are already in a tree are moved
+func (r Root) Prepend(nodes ...Root) error {
+	if r.Pointer == nil {
+		return modificationError("nothing to prepend to")
+	}
+	return insertNodes(r.Pointer, r.Pointer.FirstChild, nodes)
+}
+
+// insertNodes inserts nodes as children of parent before the child
+// before, or at the end if before is nil
+func insertNodes(parent, before *html.Node, nodes []Root) error {
+	for _, n := range nodes {
+		if err := checkInsert(parent, n.Pointer); err != nil {
+			return err
+		}
+	}
+	for _, n := range nodes {
+		if n.Pointer == before {
+			before = before.NextSibling
+		}
+		detach(n.Pointer)
+		parent.InsertBefore(n.Pointer, before)
+	}
+	return nil
+}
+
+// checkInsert makes sure node can be made a child of parent
+func checkInsert(parent, node *html.Node) error {
+	if node == nil {
+		return modificationError("nothing to insert")
+	}
+	if node.Type == html.DocumentNode {
+		return modificationError("cannot insert a document node")
+	}
+	for p := parent; p != nil; p = p.Parent {
+		if p == node {
+			return modificationError("cannot insert a node inside itself")
+		}
+	}
+	return nil
+}
+
+// detach removes n from its parent, if it has one
+func detach(n *html.Node) {
+	p := n.Parent
+	if p == nil {
+		return
+	}
+	// nodes made up for attributes by XPath point to their element without being one of its children
+	if n.PrevSibling == nil && p.FirstChild != n {
+		n.Parent = nil
+		return
+	}
+	p.RemoveChild(n)
+}
+
+// modificationError reports a change to the DOM that cannot be made
+func modificationError(msg string) error {
+	if debug {
+		panic("Unable to modify the DOM: " + msg)
+	}
+	return newError(ErrInvalidModification, msg)
+}
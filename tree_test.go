@@ -0,0 +1,112 @@
+package soup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const articleHTML = `<html><body><article><h1>Title</h1><div class="ad">Buy now</div><p>One <b>two</b> three</p><script>track()</script><p>Four</p></article></body></html>`
+
+func TestDecomposeAndExtract(t *testing.T) {
+	page := HTMLParse(articleHTML)
+	for _, junk := range page.Select("script, .ad") {
+		junk.Decompose()
+	}
+	article := page.Find("article")
+	assert.Equal(t, "<article><h1>Title</h1><p>One <b>two</b> three</p><p>Four</p></article>", article.HTML())
+
+	h1 := page.Find("h1").Extract()
+	assert.NoError(t, h1.Error)
+	assert.Nil(t, h1.Pointer.Parent)
+	assert.Equal(t, "<h1>Title</h1>", h1.HTML())
+	assert.Equal(t, "One two threeFour", article.FullText())
+}
+
+func TestReplaceWithAndWrap(t *testing.T) {
+	page := HTMLParse(articleHTML)
+	em := HTMLParse("<em>2</em>").Find("em")
+	assert.NoError(t, page.Find("b").ReplaceWith(em))
+	assert.Equal(t, "<p>One <em>2</em> three</p>", page.Find("p").HTML())
+
+	section := HTMLParse("<section></section>").Find("section")
+	wrapped := page.Find("p").Wrap(section)
+	assert.NoError(t, wrapped.Error)
+	assert.Equal(t, "section", wrapped.NodeValue)
+	assert.Equal(t, "<section><p>One <em>2</em> three</p></section>", page.Find("section").HTML())
+	assert.Equal(t, "div", page.Find("section").FindPrevElementSibling().NodeValue)
+
+	assert.NoError(t, page.Find("section").Unwrap())
+	assert.Equal(t, "div", page.Find("p").FindPrevElementSibling().NodeValue)
+	assert.Equal(t, ErrElementNotFound, page.Find("section").Error.(Error).Type)
+}
+
+func TestInsertAndAppend(t *testing.T) {
+	page := HTMLParse(articleHTML)
+	article := page.Find("article")
+	h1, script := page.Find("h1"), page.Find("script")
+
+	// moving nodes which are already in the tree
+	assert.NoError(t, article.Append(h1))
+	assert.Equal(t, "h1", article.ElementChildren()[4].NodeValue)
+	assert.NoError(t, article.Prepend(script))
+	assert.Equal(t, "script", article.ElementChildren()[0].NodeValue)
+	assert.NoError(t, page.Find("div").InsertBefore(h1))
+	assert.NoError(t, page.Find("div").InsertAfter(script, HTMLParse("<hr>").Find("hr")))
+
+	var names []string
+	for _, c := range article.ElementChildren() {
+		names = append(names, c.NodeValue)
+	}
+	assert.Equal(t, []string{"h1", "div", "script", "hr", "p", "p"}, names)
+
+	page.Find("p").Clear()
+	assert.Equal(t, "<p></p>", page.Find("p").HTML())
+}
+
+func TestInvalidModification(t *testing.T) {
+	page := HTMLParse(articleHTML)
+	article := page.Find("article")
+
+	err := page.Find("p").Append(article)
+	assert.Equal(t, ErrInvalidModification, err.(Error).Type)
+	err = article.Append(article)
+	assert.Equal(t, ErrInvalidModification, err.(Error).Type)
+	err = page.Find("missing").ReplaceWith(article)
+	assert.Equal(t, ErrInvalidModification, err.(Error).Type)
+	err = page.Find("p").Extract().Unwrap()
+	assert.Equal(t, ErrInvalidModification, err.(Error).Type)
+	wrapped := page.Find("h1").Wrap(article)
+	assert.Equal(t, ErrInvalidModification, wrapped.Error.(Error).Type)
+	assert.Equal(t, "h1", article.ElementChildren()[0].NodeValue)
+}
+
+func TestNewNodes(t *testing.T) {
+	link := NewTag("a", map[string]string{"href": "/next", "class": "pager"})
+	assert.Equal(t, `<a class="pager" href="/next"></a>`, link.HTML())
+	assert.NoError(t, link.Append(NewText("Next <page>")))
+	assert.Equal(t, `<a class="pager" href="/next">Next &lt;page&gt;</a>`, link.HTML())
+
+	page := HTMLParse(articleHTML)
+	assert.NoError(t, page.Find("article").Append(link, NewComment(" end ")))
+	assert.Equal(t, "/next", page.Find("a", "class", "pager").Attrs()["href"])
+	assert.Equal(t, " end ", page.Find("article").Children()[6].NodeValue)
+	assert.Equal(t, "a", NewTag("a", nil).NodeValue)
+}
+
+func TestParseFragment(t *testing.T) {
+	nodes, err := ParseFragment(`<li>One</li><li>Two</li>`, Root{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(nodes))
+	assert.Nil(t, nodes[0].Pointer.Parent)
+
+	page := HTMLParse(`<table><tbody></tbody></table>`)
+	tbody := page.Find("tbody")
+	rows, err := ParseFragment(`<tr><td>cell</td></tr>`, tbody)
+	assert.NoError(t, err)
+	assert.NoError(t, tbody.Append(rows...))
+	assert.Equal(t, "cell", page.Find("td").Text())
+
+	_, err = ParseFragment(`<p>`, NewText("text"))
+	assert.Equal(t, ErrUnableToParse, err.(Error).Type)
+}
@@ -0,0 +1,191 @@
+package soup
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// xmlDocument marks the document node of a DOM built by XMLParse,
+// whose names are compared case-sensitively
+const xmlDocument = "#xml"
+
+// cdataNamespace marks text nodes which were CDATA sections in the XML,
+// so that they are written out as CDATA sections again
+const cdataNamespace = "#cdata"
+
+// xmlEncoding finds the encoding in the XML declaration of a document
+var xmlEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([^"']+)["']`)
+
+// inXMLDocument reports whether n is part of a DOM built by XMLParse
+func inXMLDocument(n *html.Node) bool {
+	for n.Parent != nil {
+		n = n.Parent
+	}
+	return n.Type == html.DocumentNode && n.Data == xmlDocument
+}
+
+// XMLParse parses an XML document such as a feed, a sitemap or an API
+// response, and returns a pointer to its root element. Unlike HTMLParse,
+// names keep their case and are compared case-sensitively, and CDATA
+// sections are kept. Elements and attributes keep their namespace prefix
+// in Pointer.Namespace and their local name in Pointer.Data, so that
+// <atom:link> can be found with Find("atom:link"), the CSS selector
+// "atom|link" or the XPath expression //atom:link
+func XMLParse(s string) Root {
+	d := xml.NewDecoder(strings.NewReader(s))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	// the string is UTF-8 whatever the declaration says
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	doc := &html.Node{Type: html.DocumentNode, Data: xmlDocument}
+	parent := doc
+	for {
+		offset := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if debug {
+				panic("Unable to parse the XML")
+			}
+			return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to parse the XML: %v", err))}
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &html.Node{Type: html.ElementNode, Data: tok.Name.Local, Namespace: tok.Name.Space}
+			for _, attr := range tok.Attr {
+				n.Attr = append(n.Attr, html.Attribute{Namespace: attr.Name.Space, Key: attr.Name.Local, Val: attr.Value})
+			}
+			parent.AppendChild(n)
+			parent = n
+		case xml.EndElement:
+			// close the matching element, along with any left open inside it
+			for p := parent; p != doc; p = p.Parent {
+				if p.Data == tok.Name.Local && p.Namespace == tok.Name.Space {
+					parent = p.Parent
+					break
+				}
+			}
+		case xml.CharData:
+			n := &html.Node{Type: html.TextNode, Data: string(tok)}
+			if strings.HasPrefix(s[offset:d.InputOffset()], "<![CDATA[") {
+				n.Namespace = cdataNamespace
+			} else if parent == doc && strings.TrimSpace(n.Data) == "" {
+				continue
+			}
+			parent.AppendChild(n)
+		case xml.Comment:
+			parent.AppendChild(&html.Node{Type: html.CommentNode, Data: string(tok)})
+		case xml.ProcInst:
+			inst := "<?" + tok.Target
+			if len(tok.Inst) > 0 {
+				inst += " " + string(tok.Inst)
+			}
+			parent.AppendChild(&html.Node{Type: html.RawNode, Data: inst + "?>"})
+		case xml.Directive:
+			parent.AppendChild(&html.Node{Type: html.RawNode, Data: "<!" + string(tok) + ">"})
+		}
+	}
+	for r := doc.FirstChild; r != nil; r = r.NextSibling {
+		if r.Type == html.ElementNode {
+			return Root{Pointer: r, NodeValue: r.Data}
+		}
+	}
+	if debug {
+		panic("No root element found in the XML")
+	}
+	return Root{Error: newError(ErrUnableToParse, "no root element found in the XML")}
+}
+
+// XMLParseReader is like XMLParse, but reads the document from r. The
+// document is converted to UTF-8 from the encoding named by its byte order
+// mark or XML declaration, which is returned by Encoding
+func XMLParseReader(r io.Reader) Root {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		if debug {
+			panic("Unable to read the XML")
+		}
+		return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to read the XML: %v", err))}
+	}
+	label := "utf-8"
+	switch {
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		label = "utf-16be"
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		label = "utf-16le"
+	case byteOrderMark(data) == 0:
+		if m := xmlEncoding.FindSubmatch(data); m != nil {
+			label = string(m[1])
+		}
+	}
+	data = data[byteOrderMark(data):]
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		if debug {
+			panic("Unknown XML encoding " + label)
+		}
+		return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unknown XML encoding %q", label))}
+	}
+	if name != "utf-8" {
+		if data, err = enc.NewDecoder().Bytes(data); err != nil {
+			if debug {
+				panic("Unable to decode the XML from " + name)
+			}
+			return Root{Error: newError(ErrUnableToParse, fmt.Sprintf("unable to decode the XML from %s: %v", name, err))}
+		}
+	}
+	root := XMLParse(string(data))
+	if root.Error == nil {
+		root.Pointer.Parent.Attr = append(root.Pointer.Parent.Attr, html.Attribute{Key: encodingAttr, Val: name})
+	}
+	return root
+}
+
+// NamespaceURI returns the URI of the namespace the element is in. For XML
+// it is looked up from the xmlns attributes of the element and its
+// ancestors, HTML elements are in the XHTML, SVG or MathML namespace
+func (r Root) NamespaceURI() string {
+	if r.Pointer == nil || r.Pointer.Type != html.ElementNode {
+		return ""
+	}
+	prefix := r.Pointer.Namespace
+	if !inXMLDocument(r.Pointer) {
+		switch prefix {
+		case "svg":
+			return "http://www.w3.org/2000/svg"
+		case "math":
+			return "http://www.w3.org/1998/Math/MathML"
+		}
+		return "http://www.w3.org/1999/xhtml"
+	}
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace"
+	}
+	for n := r.Pointer; n != nil; n = n.Parent {
+		for _, attr := range n.Attr {
+			if prefix == "" && attr.Namespace == "" && attr.Key == "xmlns" ||
+				prefix != "" && attr.Namespace == "xmlns" && attr.Key == prefix {
+				return attr.Val
+			}
+		}
+	}
+	return ""
+}
+
+// XML returns the XML code for the pointer, the XML counterpart of HTML
+func (r Root) XML() (string, error) {
+	var buf strings.Builder
+	err := r.Format(&buf, FormatOptions{XML: true})
+	return buf.String(), err
+}
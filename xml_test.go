@@ -0,0 +1,185 @@
+package soup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const feedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title>Example Feed</title>
+	<link href="https://example.org/" rel="alternate"/>
+	<entry>
+		<title>First &amp; best</title>
+		<summary><![CDATA[<p>Some <b>HTML</b></p>]]></summary>
+		<media:thumbnail url="https://example.org/1.png"/>
+		<pubDate>2026-10-01</pubDate>
+	</entry>
+	<entry>
+		<title>Second</title>
+		<PubDate>2026-10-02</PubDate>
+	</entry>
+</feed>`
+
+func TestXMLParse(t *testing.T) {
+	feed := XMLParse(feedXML)
+	assert.NoError(t, feed.Error)
+	assert.Equal(t, "feed", feed.NodeValue)
+	assert.Equal(t, 2, len(feed.FindAll("entry")))
+
+	// self-closing tags don't swallow their siblings
+	link := feed.Find("link")
+	assert.Equal(t, "entry", link.FindNextElementSibling().NodeValue)
+
+	// CDATA is kept as text
+	assert.Equal(t, "<p>Some <b>HTML</b></p>", feed.Find("summary").FullText())
+	assert.Equal(t, "First & best", feed.Find("entry").Find("title").Text())
+
+	// names are case-sensitive
+	assert.Equal(t, 1, len(feed.FindAll("pubDate")))
+	assert.Equal(t, 0, len(feed.FindAll("pubdate")))
+	assert.Equal(t, 1, len(feed.Select("PubDate")))
+	assert.Equal(t, "2026-10-01", feed.SelectOne("pubDate").Text())
+	nodes, err := feed.XPath("//pubDate")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(nodes))
+	assert.Equal(t, 0, len(feed.Select("[REL]")))
+}
+
+func TestXMLNamespaces(t *testing.T) {
+	feed := XMLParse(feedXML)
+	thumb := feed.Find("media:thumbnail")
+	assert.NoError(t, thumb.Error)
+	assert.Equal(t, "thumbnail", thumb.NodeValue)
+	assert.Equal(t, "media", thumb.Pointer.Namespace)
+	assert.Equal(t, "http://search.yahoo.com/mrss/", thumb.NamespaceURI())
+	assert.Equal(t, "http://www.w3.org/2005/Atom", feed.Find("entry").NamespaceURI())
+	assert.Equal(t, thumb.Pointer, feed.SelectOne("media|thumbnail").Pointer)
+	nodes, err := feed.XPath("//media:thumbnail/@url")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org/1.png", nodes[0].Pointer.Data)
+
+	assert.Equal(t, "http://www.w3.org/1999/xhtml", HTMLParse("<p>x</p>").Find("p").NamespaceURI())
+	assert.Equal(t, "http://www.w3.org/2000/svg", HTMLParse("<svg><rect/></svg>").Find("rect").NamespaceURI())
+}
+
+const rssXML = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+	<channel>
+		<atom:link href="https://example.org/feed.xml" rel="self"/>
+		<link>https://example.org/</link>
+		<item><title>One</title><link>https://example.org/1</link></item>
+	</channel>
+</rss>`
+
+func TestXMLPrefixedNames(t *testing.T) {
+	rss := XMLParse(rssXML)
+
+	// names without a prefix don't match prefixed elements
+	link := rss.Find("link")
+	assert.Equal(t, "", link.Pointer.Namespace)
+	assert.Equal(t, "https://example.org/", link.Text())
+	assert.Len(t, rss.FindAll("link"), 2)
+	assert.Len(t, rss.FindBy(Tag(Exact("link"))).Pointer.Attr, 0)
+	assert.Equal(t, "atom", rss.FindBy(Tag(Exact("atom:link"))).Pointer.Namespace)
+
+	atom := rss.Find("atom:link")
+	assert.Equal(t, "atom", atom.Pointer.Namespace)
+	href, _ := atom.Attr("href")
+	assert.Equal(t, "https://example.org/feed.xml", href)
+	assert.Len(t, rss.FindAll("atom:link"), 1)
+
+	// selectors compare names the same way, with *|link for any prefix
+	assert.Len(t, rss.Select("link"), 2)
+	assert.Equal(t, "https://example.org/", rss.SelectOne("channel > link").Text())
+	assert.Len(t, rss.Select("atom|link"), 1)
+	assert.Len(t, rss.Select("*|link"), 3)
+	assert.Len(t, rss.Select("channel > *"), 3)
+
+	nodes, err := rss.XPath("//link")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	for _, n := range nodes {
+		assert.Equal(t, "", n.Pointer.Namespace)
+	}
+	nodes, err = rss.XPath("//atom:link")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+	nodes, err = rss.XPath("//channel/*")
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+
+	// HTML can't have a <link> with children, so XML is written instead
+	assert.Equal(t, "<item><title>One</title><link>https://example.org/1</link></item>", rss.Find("item").HTML())
+	xml, err := rss.XML()
+	assert.NoError(t, err)
+	assert.Equal(t, xml, rss.HTML())
+
+	// HTML elements are still found by their local name
+	assert.NoError(t, HTMLParse("<svg><rect/></svg>").Find("rect").Error)
+}
+
+func TestXMLRenderers(t *testing.T) {
+	rss := XMLParse("<rss><channel><atom:link href=\"x\"/><link>http://x</link></channel></rss>")
+	var buf strings.Builder
+	n, err := rss.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, `<rss><channel><atom:link href="x"/><link>http://x</link></channel></rss>`, buf.String())
+	assert.Equal(t, int64(buf.Len()), n)
+	assert.Equal(t, rss.HTML(), buf.String())
+
+	buf.Reset()
+	assert.NoError(t, rss.Format(&buf, FormatOptions{}))
+	assert.Equal(t, rss.HTML(), buf.String())
+
+	inner, err := rss.Find("channel").InnerHTML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<atom:link href="x"/><link>http://x</link>`, inner)
+
+	out, err := rss.Prettify("  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "<rss>\n  <channel>\n    <atom:link href=\"x\"/>\n    <link>http://x</link>\n  </channel>\n</rss>\n", out)
+}
+
+func TestXMLOutput(t *testing.T) {
+	feed := XMLParse(feedXML)
+	out, err := feed.Find("entry").XML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<entry>
+		<title>First &amp; best</title>
+		<summary><![CDATA[<p>Some <b>HTML</b></p>]]></summary>
+		<media:thumbnail url="https://example.org/1.png"/>
+		<pubDate>2026-10-01</pubDate>
+	</entry>`, out)
+
+	var buf strings.Builder
+	doc := Root{Pointer: feed.Pointer.Parent}
+	assert.NoError(t, doc.Format(&buf, FormatOptions{XML: true, Pretty: true, Indent: "  "}))
+	assert.True(t, strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+  <title>Example Feed</title>
+  <link href="https://example.org/" rel="alternate"/>
+  <entry>
+    <title>First &amp; best</title>
+    <summary><![CDATA[<p>Some <b>HTML</b></p>]]></summary>
+`), buf.String())
+
+	// the output parses back to the same document
+	again, err := XMLParse(out).XML()
+	assert.NoError(t, err)
+	assert.Equal(t, out, again)
+}
+
+func TestXMLParseReader(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String(`<?xml version="1.0" encoding="ISO-8859-1"?><urlset><url><loc>https://example.org/café</loc></url></urlset>`)
+	sitemap := XMLParseReader(strings.NewReader(latin1))
+	assert.NoError(t, sitemap.Error)
+	assert.Equal(t, "https://example.org/café", sitemap.Find("loc").Text())
+
+	assert.Equal(t, ErrUnableToParse, XMLParse("<a><b></a").Error.(Error).Type)
+	assert.Equal(t, ErrUnableToParse, XMLParse("  ").Error.(Error).Type)
+	assert.Equal(t, ErrUnableToParse, XMLParseReader(strings.NewReader(`<?xml version="1.0" encoding="bogus"?><a/>`)).Error.(Error).Type)
+}
@@ -0,0 +1,1418 @@
+package soup
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// XPath is a compiled XPath 1.0 expression. It holds no state from
+// one evaluation to the next, so it can be used from several goroutines
+type XPath struct {
+	expr string
+	root xpathExpr
+}
+
+// CompileXPath parses an XPath 1.0 expression so that it can be
+// evaluated against any number of elements
+func CompileXPath(expr string) (*XPath, error) {
+	p := &xpathParser{expr: expr}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != xtEOF {
+		return nil, p.errorf("unexpected `%s`", t.val)
+	}
+	return &XPath{expr: expr, root: root}, nil
+}
+
+// MustCompileXPath is like CompileXPath but panics if the expression cannot be parsed
+func MustCompileXPath(expr string) *XPath {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		panic(err.Error())
+	}
+	return x
+}
+
+// String returns the source text of the expression
+func (x *XPath) String() string {
+	return x.expr
+}
+
+// Evaluate evaluates the expression with r as the context node. The result
+// is a []Root for node-sets, and a string, float64 or bool otherwise.
+// Attribute nodes are returned as text nodes holding the attribute value,
+// with the element they belong to as their parent
+func (x *XPath) Evaluate(r Root) (interface{}, error) {
+	if r.Pointer == nil {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		return nil, newError(ErrElementNotFound, "no element to evaluate `"+x.expr+"` against")
+	}
+	c := &xpathContext{node: xpathNode{node: r.Pointer, attr: -1}, pos: 1, size: 1, eval: &xpathEvaluation{}}
+	v, err := x.root.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, ok := v.(xpathNodeSet); ok {
+		return nodes.roots(), nil
+	}
+	return v, nil
+}
+
+// Select evaluates the expression with r as the context node, failing
+// if the expression does not produce a node-set
+func (x *XPath) Select(r Root) ([]Root, error) {
+	v, err := x.Evaluate(r)
+	if err != nil {
+		return nil, err
+	}
+	roots, ok := v.([]Root)
+	if !ok {
+		return nil, newError(ErrInvalidXPath, fmt.Sprintf("XPath `%s` does not evaluate to a node-set", x.expr))
+	}
+	return roots, nil
+}
+
+// XPath evaluates an XPath 1.0 expression with r as the context node
+// and returns the nodes it selects
+func (r Root) XPath(expr string) ([]Root, error) {
+	x, err := CompileXPath(expr)
+	if err != nil {
+		if debug {
+			panic("Invalid XPath `" + expr + "`")
+		}
+		return nil, err
+	}
+	return x.Select(r)
+}
+
+// xpathNode is a node of the XPath data model. Attributes are not
+// nodes of their own in the html package, so they are identified
+// by their element and index, with attr set to -1 for other nodes
+type xpathNode struct {
+	node *html.Node
+	attr int
+}
+
+func (n xpathNode) isAttr() bool {
+	return n.attr >= 0
+}
+
+// stringValue returns the string-value of n as defined by XPath
+func (n xpathNode) stringValue() string {
+	if n.isAttr() {
+		return n.node.Attr[n.attr].Val
+	}
+	switch n.node.Type {
+	case html.TextNode, html.CommentNode:
+		return n.node.Data
+	}
+	var buf strings.Builder
+	walkDescendants(n.node, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			buf.WriteString(c.Data)
+		}
+		return false
+	})
+	return buf.String()
+}
+
+func (n xpathNode) root() Root {
+	if n.isAttr() {
+		val := n.node.Attr[n.attr].Val
+		return Root{Pointer: &html.Node{Type: html.TextNode, Data: val, Parent: n.node}, NodeValue: val}
+	}
+	return Root{Pointer: n.node, NodeValue: n.node.Data}
+}
+
+// xpathNodeSet is a node-set value, kept in document order
+type xpathNodeSet []xpathNode
+
+func (ns xpathNodeSet) roots() []Root {
+	roots := make([]Root, 0, len(ns))
+	for _, n := range ns {
+		roots = append(roots, n.root())
+	}
+	return roots
+}
+
+// xpathEvaluation holds the state shared by a single evaluation
+type xpathEvaluation struct {
+	order map[*html.Node]int
+}
+
+// sort puts ns into document order and removes duplicates
+func (e *xpathEvaluation) sort(ns xpathNodeSet) xpathNodeSet {
+	if len(ns) < 2 {
+		return ns
+	}
+	if e.order == nil {
+		e.order = make(map[*html.Node]int)
+		i := 0
+		top := ns[0].node
+		for top.Parent != nil {
+			top = top.Parent
+		}
+		e.order[top] = i
+		walkDescendants(top, func(c *html.Node) bool {
+			i++
+			e.order[c] = i
+			return false
+		})
+	}
+	sort.SliceStable(ns, func(i, j int) bool {
+		a, b := e.order[ns[i].node], e.order[ns[j].node]
+		if a != b {
+			return a < b
+		}
+		return ns[i].attr < ns[j].attr
+	})
+	out := ns[:1]
+	for _, n := range ns[1:] {
+		if n != out[len(out)-1] {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// xpathContext is the context an expression is evaluated in
+type xpathContext struct {
+	node      xpathNode
+	pos, size int
+	eval      *xpathEvaluation
+}
+
+// xpathExpr is a node of a parsed XPath expression. Evaluating it
+// produces an xpathNodeSet, string, float64 or bool
+type xpathExpr interface {
+	eval(c *xpathContext) (interface{}, error)
+}
+
+type xpathLiteral string
+
+func (e xpathLiteral) eval(*xpathContext) (interface{}, error) {
+	return string(e), nil
+}
+
+type xpathNumber float64
+
+func (e xpathNumber) eval(*xpathContext) (interface{}, error) {
+	return float64(e), nil
+}
+
+type xpathNegate struct {
+	expr xpathExpr
+}
+
+func (e xpathNegate) eval(c *xpathContext) (interface{}, error) {
+	v, err := e.expr.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	return -xpathToNumber(v), nil
+}
+
+type xpathBinary struct {
+	op          string
+	left, right xpathExpr
+}
+
+func (e xpathBinary) eval(c *xpathContext) (interface{}, error) {
+	l, err := e.left.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "or", "and":
+		if xpathToBoolean(l) == (e.op == "or") {
+			return e.op == "or", nil
+		}
+		r, err := e.right.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		return xpathToBoolean(r), nil
+	}
+	r, err := e.right.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "|":
+		ln, lok := l.(xpathNodeSet)
+		rn, rok := r.(xpathNodeSet)
+		if !lok || !rok {
+			return nil, newError(ErrInvalidXPath, "operands of `|` must be node-sets")
+		}
+		union := make(xpathNodeSet, 0, len(ln)+len(rn))
+		return c.eval.sort(append(append(union, ln...), rn...)), nil
+	case "=", "!=", "<", "<=", ">", ">=":
+		return xpathCompare(e.op, l, r), nil
+	}
+	a, b := xpathToNumber(l), xpathToNumber(r)
+	switch e.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "div":
+		return a / b, nil
+	default:
+		return math.Mod(a, b), nil
+	}
+}
+
+// xpathCompare compares two values following the XPath rules, under
+// which a node-set compares true if any of its nodes does
+func xpathCompare(op string, l, r interface{}) bool {
+	// a node-set is compared with a boolean by converting it to a boolean
+	_, lb := l.(bool)
+	_, rb := r.(bool)
+	if _, ok := r.(xpathNodeSet); ok && lb {
+		r = xpathToBoolean(r)
+	} else if _, ok := l.(xpathNodeSet); ok && rb {
+		l = xpathToBoolean(l)
+	}
+	if ln, ok := l.(xpathNodeSet); ok {
+		for _, n := range ln {
+			if xpathCompare(op, n.stringValueAs(r), r) {
+				return true
+			}
+		}
+		return false
+	}
+	if rn, ok := r.(xpathNodeSet); ok {
+		for _, n := range rn {
+			if xpathCompare(op, l, n.stringValueAs(l)) {
+				return true
+			}
+		}
+		return false
+	}
+	if op == "=" || op == "!=" {
+		var eq bool
+		_, lb = l.(bool)
+		_, rb = r.(bool)
+		_, lf := l.(float64)
+		_, rf := r.(float64)
+		switch {
+		case lb || rb:
+			eq = xpathToBoolean(l) == xpathToBoolean(r)
+		case lf || rf:
+			eq = xpathToNumber(l) == xpathToNumber(r)
+		default:
+			eq = xpathToString(l) == xpathToString(r)
+		}
+		return eq == (op == "=")
+	}
+	a, b := xpathToNumber(l), xpathToNumber(r)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+// stringValueAs converts n for comparison with a value of the type of other
+func (n xpathNode) stringValueAs(other interface{}) interface{} {
+	if _, ok := other.(float64); ok {
+		return xpathToNumber(n.stringValue())
+	}
+	return n.stringValue()
+}
+
+// xpathPath is a location path, optionally starting from the result
+// of a filter expression rather than the context node or the root
+type xpathPath struct {
+	filter   xpathExpr
+	absolute bool
+	steps    []xpathStep
+}
+
+func (e xpathPath) eval(c *xpathContext) (interface{}, error) {
+	var nodes xpathNodeSet
+	switch {
+	case e.filter != nil:
+		v, err := e.filter.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if nodes, ok = v.(xpathNodeSet); !ok {
+			return nil, newError(ErrInvalidXPath, "only node-sets can be followed by a location path")
+		}
+	case e.absolute:
+		top := c.node.node
+		for top.Parent != nil {
+			top = top.Parent
+		}
+		nodes = xpathNodeSet{{node: top, attr: -1}}
+	default:
+		nodes = xpathNodeSet{c.node}
+	}
+	for _, s := range e.steps {
+		var next xpathNodeSet
+		for _, n := range nodes {
+			selected, err := s.apply(n, c.eval)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, selected...)
+		}
+		nodes = c.eval.sort(next)
+	}
+	return nodes, nil
+}
+
+// xpathFilter applies predicates to the result of a primary expression
+type xpathFilter struct {
+	primary xpathExpr
+	preds   []xpathExpr
+}
+
+func (e xpathFilter) eval(c *xpathContext) (interface{}, error) {
+	v, err := e.primary.eval(c)
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := v.(xpathNodeSet)
+	if !ok {
+		return nil, newError(ErrInvalidXPath, "predicates can only be applied to node-sets")
+	}
+	for _, pred := range e.preds {
+		if nodes, err = xpathApplyPredicate(pred, nodes, c.eval); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// xpathApplyPredicate keeps the nodes for which pred holds, with the
+// position of each node taken from its index in nodes
+func xpathApplyPredicate(pred xpathExpr, nodes xpathNodeSet, eval *xpathEvaluation) (xpathNodeSet, error) {
+	var kept xpathNodeSet
+	for i, n := range nodes {
+		v, err := pred.eval(&xpathContext{node: n, pos: i + 1, size: len(nodes), eval: eval})
+		if err != nil {
+			return nil, err
+		}
+		if f, ok := v.(float64); ok {
+			if f == float64(i+1) {
+				kept = append(kept, n)
+			}
+		} else if xpathToBoolean(v) {
+			kept = append(kept, n)
+		}
+	}
+	return kept, nil
+}
+
+// xpathStep is a single step of a location path
+type xpathStep struct {
+	axis  string
+	test  xpathNodeTest
+	preds []xpathExpr
+}
+
+// apply returns the nodes selected by the step from n, in the
+// order of its axis so that predicates see proximity positions
+func (s xpathStep) apply(n xpathNode, eval *xpathEvaluation) (xpathNodeSet, error) {
+	var nodes xpathNodeSet
+	xpathAxis(s.axis, n, func(m xpathNode) {
+		if s.test.match(m, s.axis == "attribute") {
+			nodes = append(nodes, m)
+		}
+	})
+	var err error
+	for _, pred := range s.preds {
+		if nodes, err = xpathApplyPredicate(pred, nodes, eval); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// xpathAxis calls f on each node along the axis from n, nearest first
+func xpathAxis(axis string, n xpathNode, f func(xpathNode)) {
+	node := func(m *html.Node) xpathNode { return xpathNode{node: m, attr: -1} }
+	var descendants func(m *html.Node)
+	descendants = func(m *html.Node) {
+		for c := m.FirstChild; c != nil; c = c.NextSibling {
+			if xpathVisible(c) {
+				f(node(c))
+				descendants(c)
+			}
+		}
+	}
+	var reverseDescendants func(m *html.Node)
+	reverseDescendants = func(m *html.Node) {
+		for c := m.LastChild; c != nil; c = c.PrevSibling {
+			if xpathVisible(c) {
+				reverseDescendants(c)
+				f(node(c))
+			}
+		}
+	}
+	// the parent of an attribute is its element, but it has no children or siblings
+	parent := n.node.Parent
+	if n.isAttr() {
+		parent = n.node
+	}
+	switch axis {
+	case "self":
+		f(n)
+	case "child":
+		if !n.isAttr() {
+			for c := n.node.FirstChild; c != nil; c = c.NextSibling {
+				if xpathVisible(c) {
+					f(node(c))
+				}
+			}
+		}
+	case "descendant", "descendant-or-self":
+		if axis == "descendant-or-self" {
+			f(n)
+		}
+		if !n.isAttr() {
+			descendants(n.node)
+		}
+	case "parent":
+		if parent != nil {
+			f(node(parent))
+		}
+	case "ancestor", "ancestor-or-self":
+		if axis == "ancestor-or-self" {
+			f(n)
+		}
+		for p := parent; p != nil; p = p.Parent {
+			f(node(p))
+		}
+	case "following-sibling", "preceding-sibling":
+		if n.isAttr() {
+			return
+		}
+		for s := xpathSibling(n.node, axis == "following-sibling"); s != nil; s = xpathSibling(s, axis == "following-sibling") {
+			f(node(s))
+		}
+	case "following":
+		start := n.node
+		if n.isAttr() {
+			descendants(start)
+		}
+		for m := start; m != nil; m = m.Parent {
+			for s := xpathSibling(m, true); s != nil; s = xpathSibling(s, true) {
+				f(node(s))
+				descendants(s)
+			}
+		}
+	case "preceding":
+		for m := n.node; m != nil; m = m.Parent {
+			for s := xpathSibling(m, false); s != nil; s = xpathSibling(s, false) {
+				reverseDescendants(s)
+				f(node(s))
+			}
+		}
+	case "attribute":
+		if !n.isAttr() && n.node.Type == html.ElementNode {
+			for i := range n.node.Attr {
+				f(xpathNode{node: n.node, attr: i})
+			}
+		}
+	}
+}
+
+// xpathVisible reports whether m is part of the XPath data model,
+// which has no doctype nodes
+func xpathVisible(m *html.Node) bool {
+	return m.Type != html.DoctypeNode
+}
+
+// xpathSibling returns the next or previous sibling of m in the XPath data model
+func xpathSibling(m *html.Node, next bool) *html.Node {
+	for {
+		if next {
+			m = m.NextSibling
+		} else {
+			m = m.PrevSibling
+		}
+		if m == nil || xpathVisible(m) {
+			return m
+		}
+	}
+}
+
+// xpathNodeTest is the node test of a step: a name test, possibly
+// with a `*` wildcard, or one of the node type tests
+type xpathNodeTest struct {
+	kind   string
+	prefix string
+	local  string
+}
+
+// match checks m against the test, where principal name tests only
+// match attributes on the attribute axis and elements otherwise
+func (t xpathNodeTest) match(m xpathNode, attrAxis bool) bool {
+	switch t.kind {
+	case "node":
+		return true
+	case "text":
+		return !m.isAttr() && m.node.Type == html.TextNode
+	case "comment":
+		return !m.isAttr() && m.node.Type == html.CommentNode
+	case "processing-instruction":
+		return false
+	}
+	if attrAxis {
+		if !m.isAttr() {
+			return false
+		}
+		attr := m.node.Attr[m.attr]
+		if !matchPrefix(m.node, attr.Namespace, t.prefix, t.local) {
+			return false
+		}
+		return t.local == "*" || matchName(m.node, attr.Key, t.local)
+	}
+	if m.isAttr() || m.node.Type != html.ElementNode {
+		return false
+	}
+	if !matchPrefix(m.node, m.node.Namespace, t.prefix, t.local) {
+		return false
+	}
+	return t.local == "*" || matchTagName(m.node, t.local)
+}
+
+// matchPrefix reports whether the namespace prefix of a name of n fits the
+// prefix of a name test. In XML documents, a name test without a prefix
+// only matches names without one, as XPath has it. HTML elements keep
+// matching whatever namespace the parser has put them in
+func matchPrefix(n *html.Node, namespace, prefix, local string) bool {
+	if local == "*" && prefix == "" {
+		// * matches any name
+		return true
+	}
+	if prefix != "" || inXMLDocument(n) {
+		return namespace == prefix
+	}
+	return true
+}
+
+// xpathCall is a call to one of the functions of the XPath core library
+type xpathCall struct {
+	name string
+	args []xpathExpr
+}
+
+// xpathFunction describes a function of the core library
+type xpathFunction struct {
+	minArgs, maxArgs int
+	call             func(c *xpathContext, args []interface{}) (interface{}, error)
+}
+
+// xpathFunctions is the XPath 1.0 core function library, with a maxArgs
+// of -1 meaning any number of arguments
+var xpathFunctions map[string]xpathFunction
+
+func init() {
+	xpathFunctions = map[string]xpathFunction{
+		"last": {0, 0, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return float64(c.size), nil
+		}},
+		"position": {0, 0, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return float64(c.pos), nil
+		}},
+		"count": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			ns, err := xpathNodeSetArg("count", args[0])
+			return float64(len(ns)), err
+		}},
+		"id": {1, 1, xpathID},
+		"local-name": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			n, ok, err := xpathFirstNodeArg(c, "local-name", args)
+			if !ok {
+				return "", err
+			}
+			name := xpathName(n)
+			if i := strings.IndexByte(name, ':'); i >= 0 {
+				name = name[i+1:]
+			}
+			return name, nil
+		}},
+		"namespace-uri": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			n, ok, err := xpathFirstNodeArg(c, "namespace-uri", args)
+			if !ok {
+				return "", err
+			}
+			if n.isAttr() {
+				return n.node.Attr[n.attr].Namespace, nil
+			}
+			if n.node.Type == html.ElementNode {
+				return n.node.Namespace, nil
+			}
+			return "", nil
+		}},
+		"name": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			n, ok, err := xpathFirstNodeArg(c, "name", args)
+			if !ok {
+				return "", err
+			}
+			return xpathName(n), nil
+		}},
+		"string": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return c.node.stringValue(), nil
+			}
+			return xpathToString(args[0]), nil
+		}},
+		"concat": {2, -1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			var buf strings.Builder
+			for _, a := range args {
+				buf.WriteString(xpathToString(a))
+			}
+			return buf.String(), nil
+		}},
+		"starts-with": {2, 2, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return strings.HasPrefix(xpathToString(args[0]), xpathToString(args[1])), nil
+		}},
+		"contains": {2, 2, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return strings.Contains(xpathToString(args[0]), xpathToString(args[1])), nil
+		}},
+		"substring-before": {2, 2, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			s, sep := xpathToString(args[0]), xpathToString(args[1])
+			if i := strings.Index(s, sep); i >= 0 {
+				return s[:i], nil
+			}
+			return "", nil
+		}},
+		"substring-after": {2, 2, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			s, sep := xpathToString(args[0]), xpathToString(args[1])
+			if i := strings.Index(s, sep); i >= 0 {
+				return s[i+len(sep):], nil
+			}
+			return "", nil
+		}},
+		"substring": {2, 3, xpathSubstring},
+		"string-length": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			s := c.node.stringValue()
+			if len(args) > 0 {
+				s = xpathToString(args[0])
+			}
+			return float64(utf8.RuneCountInString(s)), nil
+		}},
+		"normalize-space": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			s := c.node.stringValue()
+			if len(args) > 0 {
+				s = xpathToString(args[0])
+			}
+			return strings.Join(strings.Fields(s), " "), nil
+		}},
+		"translate": {3, 3, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			from, to := []rune(xpathToString(args[1])), []rune(xpathToString(args[2]))
+			return strings.Map(func(r rune) rune {
+				for i, f := range from {
+					if f == r {
+						if i < len(to) {
+							return to[i]
+						}
+						return -1
+					}
+				}
+				return r
+			}, xpathToString(args[0])), nil
+		}},
+		"boolean": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return xpathToBoolean(args[0]), nil
+		}},
+		"not": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return !xpathToBoolean(args[0]), nil
+		}},
+		"true": {0, 0, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return true, nil
+		}},
+		"false": {0, 0, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return false, nil
+		}},
+		"lang": {1, 1, xpathLang},
+		"number": {0, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return xpathToNumber(c.node.stringValue()), nil
+			}
+			return xpathToNumber(args[0]), nil
+		}},
+		"sum": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			ns, err := xpathNodeSetArg("sum", args[0])
+			var sum float64
+			for _, n := range ns {
+				sum += xpathToNumber(n.stringValue())
+			}
+			return sum, err
+		}},
+		"floor": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return math.Floor(xpathToNumber(args[0])), nil
+		}},
+		"ceiling": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return math.Ceil(xpathToNumber(args[0])), nil
+		}},
+		"round": {1, 1, func(c *xpathContext, args []interface{}) (interface{}, error) {
+			return xpathRound(xpathToNumber(args[0])), nil
+		}},
+	}
+}
+
+func (e xpathCall) eval(c *xpathContext) (interface{}, error) {
+	args := make([]interface{}, 0, len(e.args))
+	for _, a := range e.args {
+		v, err := a.eval(c)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return xpathFunctions[e.name].call(c, args)
+}
+
+func xpathNodeSetArg(name string, v interface{}) (xpathNodeSet, error) {
+	ns, ok := v.(xpathNodeSet)
+	if !ok {
+		return nil, newError(ErrInvalidXPath, fmt.Sprintf("argument to %s() must be a node-set", name))
+	}
+	return ns, nil
+}
+
+// xpathFirstNodeArg returns the first node of the optional node-set
+// argument, or the context node when it is left out
+func xpathFirstNodeArg(c *xpathContext, name string, args []interface{}) (xpathNode, bool, error) {
+	if len(args) == 0 {
+		return c.node, true, nil
+	}
+	ns, err := xpathNodeSetArg(name, args[0])
+	if err != nil || len(ns) == 0 {
+		return xpathNode{}, false, err
+	}
+	return ns[0], true, nil
+}
+
+func xpathName(n xpathNode) string {
+	if n.isAttr() {
+		return n.node.Attr[n.attr].Key
+	}
+	if n.node.Type == html.ElementNode {
+		return n.node.Data
+	}
+	return ""
+}
+
+func xpathID(c *xpathContext, args []interface{}) (interface{}, error) {
+	var ids []string
+	if ns, ok := args[0].(xpathNodeSet); ok {
+		for _, n := range ns {
+			ids = append(ids, strings.Fields(n.stringValue())...)
+		}
+	} else {
+		ids = strings.Fields(xpathToString(args[0]))
+	}
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	top := c.node.node
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	var found xpathNodeSet
+	walkDescendants(top, func(m *html.Node) bool {
+		if m.Type == html.ElementNode {
+			for _, attr := range m.Attr {
+				if attr.Key == "id" && want[attr.Val] {
+					found = append(found, xpathNode{node: m, attr: -1})
+					delete(want, attr.Val)
+					break
+				}
+			}
+		}
+		return false
+	})
+	return found, nil
+}
+
+func xpathSubstring(c *xpathContext, args []interface{}) (interface{}, error) {
+	s := []rune(xpathToString(args[0]))
+	start := xpathRound(xpathToNumber(args[1]))
+	end := math.Inf(1)
+	if len(args) == 3 {
+		end = start + xpathRound(xpathToNumber(args[2]))
+	}
+	var buf strings.Builder
+	for i, r := range s {
+		if pos := float64(i + 1); pos >= start && pos < end {
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String(), nil
+}
+
+func xpathLang(c *xpathContext, args []interface{}) (interface{}, error) {
+	want := strings.ToLower(xpathToString(args[0]))
+	for m := c.node.node; m != nil; m = m.Parent {
+		for _, attr := range m.Attr {
+			if attr.Key == "lang" || attr.Key == "xml:lang" || (attr.Namespace == "xml" && attr.Key == "lang") {
+				lang := strings.ToLower(attr.Val)
+				return lang == want || strings.HasPrefix(lang, want+"-"), nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func xpathRound(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	if f < 0 && f >= -0.5 {
+		return math.Copysign(0, -1)
+	}
+	return math.Floor(f + 0.5)
+}
+
+// xpathNumberPattern matches the strings that XPath converts to numbers
+var xpathNumberPattern = regexp.MustCompile(`^-?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)$`)
+
+func xpathToNumber(v interface{}) float64 {
+	switch v := v.(type) {
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		s := strings.Trim(v, " \t\r\n")
+		if !xpathNumberPattern.MatchString(s) {
+			return math.NaN()
+		}
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	}
+	return xpathToNumber(xpathToString(v))
+}
+
+func xpathToString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "NaN"
+		case math.IsInf(v, 1):
+			return "Infinity"
+		case math.IsInf(v, -1):
+			return "-Infinity"
+		case v == 0:
+			return "0"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case xpathNodeSet:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0].stringValue()
+	}
+	return ""
+}
+
+func xpathToBoolean(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	case string:
+		return v != ""
+	case xpathNodeSet:
+		return len(v) > 0
+	}
+	return false
+}
+
+// xpathTokenKind identifies the kind of a lexical token of an XPath expression
+type xpathTokenKind int
+
+const (
+	xtEOF xpathTokenKind = iota
+	xtPunct
+	xtOperator
+	xtName
+	xtNodeType
+	xtFunction
+	xtAxis
+	xtLiteral
+	xtNumber
+	xtVariable
+)
+
+type xpathToken struct {
+	kind xpathTokenKind
+	val  string
+	pos  int
+}
+
+// xpathParser tokenizes and parses an XPath expression
+type xpathParser struct {
+	expr   string
+	tokens []xpathToken
+	i      int
+}
+
+func (p *xpathParser) errorf(format string, args ...interface{}) error {
+	pos := len(p.expr)
+	if p.i < len(p.tokens) {
+		pos = p.tokens[p.i].pos
+	}
+	return newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: %s at offset %d", p.expr, fmt.Sprintf(format, args...), pos))
+}
+
+var xpathNodeTypes = map[string]bool{"comment": true, "text": true, "processing-instruction": true, "node": true}
+
+var xpathAxes = map[string]bool{
+	"ancestor": true, "ancestor-or-self": true, "attribute": true, "child": true,
+	"descendant": true, "descendant-or-self": true, "following": true, "following-sibling": true,
+	"namespace": true, "parent": true, "preceding": true, "preceding-sibling": true, "self": true,
+}
+
+// lex splits the expression into tokens, using the preceding token to tell
+// the `*` and name operators apart from name tests as the XPath spec requires
+func (p *xpathParser) lex() error {
+	s := p.expr
+	i := 0
+	for {
+		for i < len(s) && isSpaceByte(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			p.tokens = append(p.tokens, xpathToken{kind: xtEOF, pos: i})
+			return nil
+		}
+		start := i
+		operatorContext := false
+		if n := len(p.tokens); n > 0 {
+			prev := p.tokens[n-1]
+			operatorContext = !(prev.kind == xtOperator ||
+				(prev.kind == xtPunct && (prev.val == "@" || prev.val == "::" || prev.val == "(" || prev.val == "[" || prev.val == ",")))
+		}
+		tok := xpathToken{pos: start}
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: unterminated string at offset %d", s, start))
+			}
+			tok.kind, tok.val = xtLiteral, s[i+1:i+1+end]
+			i += end + 2
+		case ('0' <= c && c <= '9') || (c == '.' && i+1 < len(s) && '0' <= s[i+1] && s[i+1] <= '9'):
+			for i < len(s) && (('0' <= s[i] && s[i] <= '9') || s[i] == '.') {
+				i++
+			}
+			tok.kind, tok.val = xtNumber, s[start:i]
+		case strings.HasPrefix(s[i:], ".."), strings.HasPrefix(s[i:], "::"):
+			tok.kind, tok.val = xtPunct, s[i:i+2]
+			i += 2
+		case strings.IndexByte("()[].@,", c) >= 0:
+			tok.kind, tok.val = xtPunct, s[i:i+1]
+			i++
+		case strings.HasPrefix(s[i:], "//"), strings.HasPrefix(s[i:], "!="), strings.HasPrefix(s[i:], "<="), strings.HasPrefix(s[i:], ">="):
+			tok.kind, tok.val = xtOperator, s[i:i+2]
+			i += 2
+		case c == '*' && operatorContext:
+			tok.kind, tok.val = xtOperator, "*"
+			i++
+		case c == '*':
+			tok.kind, tok.val = xtName, "*"
+			i++
+		case strings.IndexByte("/|+-=<>", c) >= 0:
+			tok.kind, tok.val = xtOperator, s[i:i+1]
+			i++
+		case c == '$':
+			i++
+			name := xpathNCName(s[i:])
+			if name == "" {
+				return newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: expected variable name at offset %d", s, i))
+			}
+			i += len(name)
+			tok.kind, tok.val = xtVariable, name
+		default:
+			name := xpathNCName(s[i:])
+			if name == "" {
+				return newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: unexpected %q at offset %d", s, c, i))
+			}
+			i += len(name)
+			if operatorContext {
+				switch name {
+				case "and", "or", "mod", "div":
+					tok.kind, tok.val = xtOperator, name
+					p.tokens = append(p.tokens, tok)
+					continue
+				}
+				return newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: unexpected `%s` at offset %d", s, name, start))
+			}
+			// a QName or a prefix:* name test, but not an axis name followed by ::
+			if i+1 < len(s) && s[i] == ':' && s[i+1] != ':' {
+				if s[i+1] == '*' {
+					name += ":*"
+					i += 2
+				} else if local := xpathNCName(s[i+1:]); local != "" {
+					name += ":" + local
+					i += 1 + len(local)
+				}
+			}
+			j := i
+			for j < len(s) && isSpaceByte(s[j]) {
+				j++
+			}
+			switch {
+			case strings.HasPrefix(s[j:], "::"):
+				tok.kind = xtAxis
+			case j < len(s) && s[j] == '(':
+				tok.kind = xtFunction
+				if xpathNodeTypes[name] {
+					tok.kind = xtNodeType
+				}
+			default:
+				tok.kind = xtName
+			}
+			tok.val = name
+		}
+		p.tokens = append(p.tokens, tok)
+	}
+}
+
+// xpathNCName returns the XML name without colons that s starts with
+func xpathNCName(s string) string {
+	for i, r := range s {
+		if r == '_' || r >= 0x80 || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+			continue
+		}
+		if i > 0 && (r == '-' || r == '.' || ('0' <= r && r <= '9')) {
+			continue
+		}
+		return s[:i]
+	}
+	return s
+}
+
+func (p *xpathParser) peek() xpathToken {
+	return p.tokens[p.i]
+}
+
+func (p *xpathParser) next() xpathToken {
+	t := p.tokens[p.i]
+	if t.kind != xtEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it has the given kind and value
+func (p *xpathParser) accept(kind xpathTokenKind, val string) bool {
+	if t := p.peek(); t.kind == kind && t.val == val {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *xpathParser) expect(kind xpathTokenKind, val string) error {
+	if !p.accept(kind, val) {
+		if t := p.peek(); t.kind != xtEOF {
+			return p.errorf("expected `%s` but found `%s`", val, t.val)
+		}
+		return p.errorf("expected `%s`", val)
+	}
+	return nil
+}
+
+// xpathPrecedence lists the binary operators from lowest to highest precedence
+var xpathPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"=", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "div", "mod"},
+}
+
+func (p *xpathParser) parseExpr() (xpathExpr, error) {
+	return p.parseBinary(0)
+}
+
+func (p *xpathParser) parseBinary(level int) (xpathExpr, error) {
+	if level == len(xpathPrecedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		matched := false
+		if t.kind == xtOperator {
+			for _, op := range xpathPrecedence[level] {
+				matched = matched || op == t.val
+			}
+		}
+		if !matched {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = xpathBinary{op: t.val, left: left, right: right}
+	}
+}
+
+func (p *xpathParser) parseUnary() (xpathExpr, error) {
+	if p.accept(xtOperator, "-") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return xpathNegate{expr: expr}, nil
+	}
+	left, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(xtOperator, "|") {
+		right, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		left = xpathBinary{op: "|", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *xpathParser) parsePath() (xpathExpr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == xtOperator && (t.val == "/" || t.val == "//"):
+		p.next()
+		path := xpathPath{absolute: true}
+		if t.val == "//" {
+			path.steps = append(path.steps, xpathDescendantOrSelf)
+		} else if !p.startsStep() {
+			return path, nil
+		}
+		return p.parseRelativePath(path)
+	case p.startsStep():
+		return p.parseRelativePath(xpathPath{})
+	}
+	primary, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	filter := xpathFilter{primary: primary}
+	for p.peek().kind == xtPunct && p.peek().val == "[" {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		filter.preds = append(filter.preds, pred)
+	}
+	var expr xpathExpr = primary
+	if len(filter.preds) > 0 {
+		expr = filter
+	}
+	if t := p.peek(); t.kind == xtOperator && (t.val == "/" || t.val == "//") {
+		p.next()
+		path := xpathPath{filter: expr}
+		if t.val == "//" {
+			path.steps = append(path.steps, xpathDescendantOrSelf)
+		}
+		return p.parseRelativePath(path)
+	}
+	return expr, nil
+}
+
+// xpathDescendantOrSelf is the step that `//` abbreviates
+var xpathDescendantOrSelf = xpathStep{axis: "descendant-or-self", test: xpathNodeTest{kind: "node"}}
+
+// startsStep reports whether the next token begins a location step
+func (p *xpathParser) startsStep() bool {
+	t := p.peek()
+	switch t.kind {
+	case xtName, xtAxis, xtNodeType:
+		return true
+	case xtPunct:
+		return t.val == "." || t.val == ".." || t.val == "@"
+	}
+	return false
+}
+
+func (p *xpathParser) parseRelativePath(path xpathPath) (xpathExpr, error) {
+	for {
+		step, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		path.steps = append(path.steps, step)
+		if p.accept(xtOperator, "//") {
+			path.steps = append(path.steps, xpathDescendantOrSelf)
+		} else if !p.accept(xtOperator, "/") {
+			return path, nil
+		}
+	}
+}
+
+func (p *xpathParser) parseStep() (xpathStep, error) {
+	if p.accept(xtPunct, ".") {
+		return xpathStep{axis: "self", test: xpathNodeTest{kind: "node"}}, nil
+	}
+	if p.accept(xtPunct, "..") {
+		return xpathStep{axis: "parent", test: xpathNodeTest{kind: "node"}}, nil
+	}
+	step := xpathStep{axis: "child"}
+	if p.accept(xtPunct, "@") {
+		step.axis = "attribute"
+	} else if t := p.peek(); t.kind == xtAxis {
+		if !xpathAxes[t.val] {
+			return step, p.errorf("unknown axis `%s`", t.val)
+		}
+		p.next()
+		step.axis = t.val
+		if err := p.expect(xtPunct, "::"); err != nil {
+			return step, err
+		}
+	}
+	t := p.next()
+	switch t.kind {
+	case xtName:
+		step.test.kind = "name"
+		step.test.local = t.val
+		if i := strings.IndexByte(t.val, ':'); i >= 0 {
+			step.test.prefix, step.test.local = t.val[:i], t.val[i+1:]
+		}
+	case xtNodeType:
+		step.test.kind = t.val
+		if err := p.expect(xtPunct, "("); err != nil {
+			return step, err
+		}
+		if t.val == "processing-instruction" && p.peek().kind == xtLiteral {
+			p.next()
+		}
+		if err := p.expect(xtPunct, ")"); err != nil {
+			return step, err
+		}
+	default:
+		p.i--
+		return step, p.errorf("expected node test")
+	}
+	for p.peek().kind == xtPunct && p.peek().val == "[" {
+		pred, err := p.parsePredicate()
+		if err != nil {
+			return step, err
+		}
+		step.preds = append(step.preds, pred)
+	}
+	return step, nil
+}
+
+func (p *xpathParser) parsePredicate() (xpathExpr, error) {
+	if err := p.expect(xtPunct, "["); err != nil {
+		return nil, err
+	}
+	pred, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return pred, p.expect(xtPunct, "]")
+}
+
+func (p *xpathParser) parsePrimary() (xpathExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case xtLiteral:
+		return xpathLiteral(t.val), nil
+	case xtNumber:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			p.i--
+			return nil, p.errorf("invalid number `%s`", t.val)
+		}
+		return xpathNumber(f), nil
+	case xtVariable:
+		p.i--
+		return nil, p.errorf("variables are not supported")
+	case xtFunction:
+		fn, ok := xpathFunctions[t.val]
+		if !ok {
+			p.i--
+			return nil, p.errorf("unknown function `%s()`", t.val)
+		}
+		call := xpathCall{name: t.val}
+		if err := p.expect(xtPunct, "("); err != nil {
+			return nil, err
+		}
+		if !p.accept(xtPunct, ")") {
+			for {
+				arg, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				if p.accept(xtPunct, ")") {
+					break
+				}
+				if err := p.expect(xtPunct, ","); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(call.args) < fn.minArgs || (fn.maxArgs >= 0 && len(call.args) > fn.maxArgs) {
+			return nil, newError(ErrInvalidXPath, fmt.Sprintf("invalid XPath `%s`: wrong number of arguments to %s() at offset %d", p.expr, t.val, t.pos))
+		}
+		return call, nil
+	case xtPunct:
+		if t.val == "(" {
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(xtPunct, ")")
+		}
+	}
+	if t.kind != xtEOF {
+		p.i--
+		return nil, p.errorf("unexpected `%s`", t.val)
+	}
+	return nil, p.errorf("unexpected end of expression")
+}
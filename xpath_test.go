@@ -0,0 +1,132 @@
+package soup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const xpathHTML = `
+<html>
+  <body>
+    <h1 id="title" lang="en-GB">Catalogue</h1>
+    <table>
+      <tr><td>Name</td><td>Widget</td></tr>
+      <tr><td>Price</td><td>  9.50  </td></tr>
+      <tr><td>Stock</td><td>12</td></tr>
+    </table>
+    <ul>
+      <li class="a">one</li>
+      <li class="b">two</li>
+      <!-- three -->
+      <li class="a">four</li>
+    </ul>
+  </body>
+</html>
+`
+
+var xpathDoc = HTMLParse(xpathHTML)
+
+func TestXPathLocationPaths(t *testing.T) {
+	price, err := xpathDoc.XPath(`//table//tr[td[1]='Price']/td[2]`)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(price)) {
+		assert.Equal(t, "  9.50  ", price[0].FullText())
+	}
+
+	items, err := xpathDoc.XPath(`//li[@class="a"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "four"}, selectedText(items))
+
+	siblings, err := xpathDoc.XPath(`//li[.='one']/following-sibling::li[1]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"two"}, selectedText(siblings))
+
+	last, err := xpathDoc.XPath(`//li[last()]/preceding-sibling::li[1]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"two"}, selectedText(last))
+
+	tables, err := xpathDoc.XPath(`//td[.='Stock']/ancestor::table`)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(tables)) {
+		assert.Equal(t, "table", tables[0].NodeValue)
+	}
+
+	comments, err := xpathDoc.XPath(`//ul/comment()`)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(comments)) {
+		assert.Equal(t, " three ", comments[0].NodeValue)
+	}
+
+	union, err := xpathDoc.XPath(`//h1 | //li[2] | //h1`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Catalogue", "two"}, selectedText(union))
+}
+
+func TestXPathRelativeToRoot(t *testing.T) {
+	ul := xpathDoc.Find("ul")
+	items, err := ul.XPath(`li[position() > 1]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"two", "four"}, selectedText(items))
+
+	parent, err := ul.XPath(`..`)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(parent)) {
+		assert.Equal(t, "body", parent[0].NodeValue)
+	}
+}
+
+func TestXPathAttributes(t *testing.T) {
+	classes, err := xpathDoc.XPath(`//li/@class`)
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(classes)) {
+		assert.Equal(t, "b", classes[1].NodeValue)
+		assert.Equal(t, "li", classes[1].Pointer.Parent.Data)
+	}
+}
+
+func TestXPathEvaluate(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected interface{}
+	}{
+		{`count(//li)`, float64(3)},
+		{`normalize-space(//tr[2]/td[2])`, "9.50"},
+		{`number(//tr[2]/td[2]) * 2`, float64(19)},
+		{`sum(//tr[position() > 1]/td[2])`, 21.5},
+		{`contains(//h1, 'log')`, true},
+		{`string(//li[@class='b'])`, "two"},
+		{`concat(substring-before('a-b', '-'), translate('abc', 'abc', 'AB'))`, "aAB"},
+		{`substring('12345', 1.5, 2.6)`, "234"},
+		{`//h1/@id = 'title' and not(//h2)`, true},
+		{`//td > 11`, true},
+		{`round(-0.4) = 0`, true},
+		{`7 mod 3 div 2`, 0.5},
+		{`name(//ul/*[1])`, "li"},
+		{`boolean(id('title')[lang('en')])`, true},
+	}
+	for _, test := range tests {
+		x, err := CompileXPath(test.expr)
+		if !assert.NoError(t, err, test.expr) {
+			continue
+		}
+		actual, err := x.Evaluate(xpathDoc)
+		assert.NoError(t, err, test.expr)
+		assert.Equal(t, test.expected, actual, test.expr)
+	}
+}
+
+func TestXPathErrors(t *testing.T) {
+	for _, expr := range []string{"", "//", "//li[", "count()", "foo()", "//li/", "child::", "$var", "1 +"} {
+		_, err := xpathDoc.XPath(expr)
+		if assert.IsType(t, Error{}, err, expr) {
+			assert.Equal(t, ErrInvalidXPath, err.(Error).Type, expr)
+		}
+	}
+
+	_, err := xpathDoc.XPath(`count(//li)`)
+	assert.Equal(t, ErrInvalidXPath, err.(Error).Type)
+
+	_, err = xpathDoc.Find("missing").XPath(`//li`)
+	assert.Equal(t, ErrElementNotFound, err.(Error).Type)
+}